@@ -0,0 +1,325 @@
+package kubectl
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// An object applied by updateResources, along with the live object it
+// replaced, if any
+type appliedObject struct {
+	manifest  string
+	namespace string
+	// nil when the object was created by the apply
+	previous resource.Object
+	// object returned by a dry-run apply, nil otherwise
+	result resource.Object
+	// whether the previous object was deleted and recreated
+	replaced bool
+}
+
+// errors of `kubectl apply` rejecting changes to immutable fields
+var immutableFieldErrors = []string{
+	"field is immutable",
+	"is immutable after creation",
+	"updates to statefulset spec for fields other than",
+}
+
+func isImmutableError(err error) bool {
+	for _, immutable := range immutableFieldErrors {
+		if strings.Contains(err.Error(), immutable) {
+			return true
+		}
+	}
+	return false
+}
+
+// Replaces the previous object of a manifest document whose changes are
+// rejected as immutable: the object is deleted, kubectl waiting for it to be
+// gone, and the document applied again. Protected objects are never
+// replaced, nor CRDs whose custom resources would be lost unless
+// allowCRDDataLoss is set.
+func replaceObject(manifestResource, namespace string, previous resource.Object,
+	owner *manifestOwner, protected *protectedObjects, allowCRDDataLoss bool,
+	stdout *bytes.Buffer, audit *manifestAudit,
+	kubectlCLIConfig *KubectlConfig) error {
+
+	if protected.protects(previous, namespace) {
+		return fmt.Errorf("refusing to replace protected object %s, whose "+
+			"changes are immutable", objectReference(previous, namespace))
+	}
+	if previous.Kind() == "CustomResourceDefinition" && !allowCRDDataLoss {
+		err := checkCRDInstances(previous.Name(), owner, kubectlCLIConfig)
+		if err != nil {
+			return err
+		}
+	}
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	deleteCommand := commandFactory.CreateDeleteByManifestCommand(
+		manifestResource, namespace)
+	start := time.Now()
+	err := deleteCommand.RunCommand()
+	audit.record("replace_delete", previous, namespace, "", start, err)
+	if err != nil {
+		return err
+	}
+
+	if kubectlCLIConfig.DryRun != "" {
+		// the previous object still exists, and would reject the apply
+		// again: the document stands for the dry-run result
+		obj, err := resource.DecodeObject(manifestResource)
+		if err != nil {
+			return err
+		}
+		return json.NewEncoder(stdout).Encode(obj)
+	}
+	applyCommand := commandFactory.CreateApplyManifestCommand(
+		manifestResource, namespace)
+	applyCommand.Stdout = stdout
+	return applyCommand.RunCommand()
+}
+
+// Lists the applied objects which were replaced
+func replacedObjects(applied []appliedObject) []string {
+	replaced := make([]string, 0)
+	for _, object := range applied {
+		if object.replaced {
+			replaced = append(replaced, objectReference(
+				auditObject(object.manifest), object.namespace))
+		}
+	}
+	return replaced
+}
+
+// Lists the manifest documents whose changes would be rejected as immutable,
+// through a server-side dry-run of their apply. Other errors are left to the
+// apply to report.
+func immutableObjects(manifestResources []string, namespace string,
+	kubectlCLIConfig *KubectlConfig) []string {
+
+	commandFactory := &CLICommandFactory{
+		KubectlConfig: kubectlCLIConfig.withDryRun(DryRunServer)}
+	immutable := make([]string, 0)
+	for _, manifestResource := range manifestResources {
+		applyCommand := commandFactory.CreateApplyManifestCommand(
+			manifestResource, namespace)
+		err := applyCommand.RunCommand()
+		if err == nil {
+			continue
+		}
+		if isImmutableError(err) {
+			immutable = append(immutable, objectReference(
+				auditObject(manifestResource), namespace))
+		} else {
+			log.Printf("[DEBUG] dry-run apply failed: %s", err)
+		}
+	}
+	return immutable
+}
+
+// Refers to an object as <namespace>/<kind>/<name>, or <kind>/<name> when it
+// is not namespaced
+func objectReference(obj resource.Object, namespace string) string {
+	if obj == nil {
+		obj = resource.Object{}
+	}
+	if obj.Namespace() != "" {
+		namespace = obj.Namespace()
+	}
+	reference := obj.Kind() + "/" + obj.Name()
+	if namespace != "" {
+		reference = namespace + "/" + reference
+	}
+	return reference
+}
+
+// Fetches the self link and uid of an applied object
+func getAppliedIdentity(manifestResource, namespace string,
+	kubectlCLIConfig *KubectlConfig) (selflink, uid string, err error) {
+
+	stdout := &bytes.Buffer{}
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	getCommand := commandFactory.CreateGetByManifestCommand(
+		manifestResource, namespace, stdout)
+
+	if err := getCommand.RunCommand(); err != nil {
+		return "", "", err
+	}
+
+	var data resource.KubectlResponse
+	if err := json.Unmarshal(stdout.Bytes(), &data); err != nil {
+		return "", "", fmt.Errorf("decoding response: %v", err)
+	}
+
+	if len(data.Items) > 1 {
+		return "", "", fmt.Errorf("Expecting a single resource, found multiple")
+	}
+	selflink = data.Items[0].Metadata.Selflink
+	if selflink == "" {
+		return "", "", fmt.Errorf("could not parse self-link from response %s",
+			stdout.String(),
+		)
+	}
+	uid = data.Items[0].Metadata.UID
+	if uid == "" {
+		return "", "", fmt.Errorf("could not parse uid from response %s",
+			stdout.String(),
+		)
+	}
+	return selflink, uid, nil
+}
+
+// Builds the self link and uid recorded for an object applied in dry-run
+// mode, reusing the ones of the live object it would replace. Objects that
+// would be created get a self link built from their type and name, and a
+// placeholder uid.
+func dryRunIdentity(result, previous resource.Object,
+	namespace string) (selflink, uid string) {
+
+	if previous != nil {
+		if values := previous.Lookup([]string{"metadata", "selfLink"}); len(values) == 1 {
+			selflink, _ = values[0].(string)
+		}
+		uid = previous.UID()
+	}
+	if ns := result.Namespace(); ns != "" {
+		namespace = ns
+	}
+	if selflink == "" {
+		selflink = "/" + result.GroupVersionKind().ResourceArg() + "/" +
+			result.Name()
+		if namespace != "" {
+			selflink = "/namespaces/" + namespace + selflink
+		}
+	}
+	if uid == "" {
+		uid = result.UID()
+	}
+	if uid == "" {
+		uid = "dry-run:" + strings.TrimPrefix(selflink, "/")
+	}
+	return selflink, uid
+}
+
+// Fetches the live object described by a manifest document, returning nil
+// when it does not exist yet
+func getLiveObject(manifestResource, namespace string,
+	kubectlCLIConfig *KubectlConfig) (resource.Object, error) {
+
+	stdout := &bytes.Buffer{}
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	getCommand := commandFactory.CreateGetIfExistsByManifestCommand(
+		manifestResource, namespace, stdout)
+
+	if err := getCommand.RunCommand(); err != nil {
+		return nil, err
+	}
+	liveObjects, err := resource.DecodeObjects(stdout.String())
+	if err != nil {
+		return nil, fmt.Errorf("decoding response: %v", err)
+	}
+	if len(liveObjects) == 0 {
+		return nil, nil
+	}
+	return liveObjects[0], nil
+}
+
+// Reverts the applied objects in reverse order: the objects created by the
+// apply are deleted, the other ones are restored to the document stored in
+// the previous state or, failing that (e.g. for redacted Secrets), to the
+// live object they replaced.
+func rollbackResources(applied []appliedObject, tfOldResources *schema.Set,
+	audit *manifestAudit, protected *protectedObjects,
+	kubectlCLIConfig *KubectlConfig) error {
+
+	previousContents := make(map[string]string)
+	for _, tfResource := range tfOldResources.List() {
+		resourceObj := tfResource.(map[string]interface{})
+		content, err := base64.StdEncoding.DecodeString(
+			resourceObj["content"].(string))
+		if err != nil {
+			continue
+		}
+		// the values of redacted Secrets cannot be restored from the state
+		if obj, err := resource.DecodeObject(string(content)); err == nil &&
+			obj.SecretDataHashed() {
+			continue
+		}
+		previousContents[resourceObj["uid"].(string)] = string(content)
+	}
+
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+
+	for i := len(applied) - 1; i >= 0; i-- {
+		object := applied[i]
+
+		if object.previous == nil {
+			obj := auditObject(object.manifest)
+			if protected.protects(obj, object.namespace) {
+				log.Printf("[WARN] rollback: not deleting protected object %s",
+					objectReference(obj, object.namespace))
+				audit.record("orphan", obj, object.namespace, "", time.Now(),
+					nil)
+				continue
+			}
+			log.Printf("[INFO] rollback: deleting newly created object")
+			deleteCommand := commandFactory.CreateDeleteByManifestCommand(
+				object.manifest, object.namespace)
+			start := time.Now()
+			err := deleteCommand.RunCommand()
+			audit.record("rollback_delete", auditObject(object.manifest),
+				object.namespace, "", start, err)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		content, ok := previousContents[object.previous.UID()]
+		if !ok {
+			restored := object.previous.Copy()
+			restored.StripServerFields()
+			encoded, err := restored.Encode()
+			if err != nil {
+				return err
+			}
+			content = encoded
+		}
+		if object.replaced {
+			// the previous object is restored once its replacement is gone
+			if protected.protects(object.previous, object.namespace) {
+				return fmt.Errorf("refusing to delete protected object %s",
+					objectReference(object.previous, object.namespace))
+			}
+			deleteCommand := commandFactory.CreateDeleteByManifestCommand(
+				object.manifest, object.namespace)
+			start := time.Now()
+			err := deleteCommand.RunCommand()
+			audit.record("rollback_delete", auditObject(object.manifest),
+				object.namespace, "", start, err)
+			if err != nil {
+				return err
+			}
+		}
+		log.Printf("[INFO] rollback: restoring %s %s",
+			object.previous.Kind(), object.previous.Name())
+		applyCommand := commandFactory.CreateApplyManifestCommand(
+			content, object.namespace)
+		start := time.Now()
+		err := applyCommand.RunCommand()
+		audit.record("rollback_restore", object.previous, object.namespace, "",
+			start, err)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
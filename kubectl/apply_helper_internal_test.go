@@ -0,0 +1,248 @@
+package kubectl
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Immutable objects", func() {
+
+	const deployment = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: default
+`
+	const immutableDeployment = deployment + `  annotations:
+    change: immutable-change
+`
+
+	const crd = `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+  uid: 7a8b
+  labels:
+    kubectl.terraform.io/manifest: 0f1e
+`
+	// custom resource of the CRD created outside of the manifest
+	const widgets = `{"kind": "List", "items": [{"apiVersion": "example.com/v1",
+		"kind": "Widget", "metadata": {"name": "gadget", "namespace": "team-a"}}]}`
+
+	owner := &manifestOwner{id: "0f1e", name: "kubectl_manifest.crds"}
+	var kubectl *fakeKubectl
+
+	BeforeEach(func() {
+		kubectl = installFakeKubectl()
+	})
+
+	AfterEach(func() {
+		kubectl.restore()
+	})
+
+	It("Should recognize immutable field errors", func() {
+		Expect(isImmutableError(errors.New(`The Job "migrate" is invalid: ` +
+			`spec.template: Invalid value: "": field is immutable`))).To(BeTrue())
+		Expect(isImmutableError(errors.New(`persistentvolumeclaims "data" ` +
+			`is invalid: spec: Forbidden: spec is immutable after creation ` +
+			`except resources.requests for bound claims`))).To(BeTrue())
+		Expect(isImmutableError(errors.New(`The StatefulSet "db" is ` +
+			`invalid: spec: Forbidden: updates to statefulset spec for ` +
+			`fields other than 'replicas' are forbidden`))).To(BeTrue())
+		Expect(isImmutableError(errors.New(`Error from server (Forbidden): ` +
+			`deployments.apps "web" is forbidden`))).To(BeFalse())
+	})
+
+	It("Should plan the replacement of immutable changes", func() {
+		immutable := immutableObjects([]string{deployment,
+			immutableDeployment}, "", &KubectlConfig{})
+
+		Expect(immutable).To(Equal([]string{"default/Deployment/web"}))
+		Expect(kubectl.commands()).To(Equal([]string{
+			"apply -f - --dry-run=server -o json",
+			"apply -f - --dry-run=server -o json"}))
+	})
+
+	It("Should delete the previous object before applying again", func() {
+		previous, _ := resource.DecodeObject(deployment)
+		stdout := &bytes.Buffer{}
+
+		err := replaceObject(deployment, "default", previous, owner, nil,
+			false, stdout, nil, &KubectlConfig{})
+		Expect(err).To(BeNil())
+		Expect(kubectl.commands()).To(Equal([]string{
+			"delete --ignore-not-found=true -f - -n default",
+			"apply -f - -n default"}))
+	})
+
+	It("Should not replace protected objects", func() {
+		previous, _ := resource.DecodeObject(deployment)
+		protected := newProtectedObjects([]string{"Deployment"}, nil)
+
+		err := replaceObject(deployment, "default", previous, owner,
+			protected, false, &bytes.Buffer{}, nil, &KubectlConfig{})
+		Expect(err).NotTo(BeNil())
+		Expect(err.Error()).To(ContainSubstring("default/Deployment/web"))
+		Expect(kubectl.commands()).To(BeEmpty())
+	})
+
+	It("Should not apply again in dry-run mode", func() {
+		previous, _ := resource.DecodeObject(deployment)
+		stdout := &bytes.Buffer{}
+
+		err := replaceObject(immutableDeployment, "default", previous, owner,
+			nil, false, stdout, nil, &KubectlConfig{DryRun: DryRunServer})
+		Expect(err).To(BeNil())
+		Expect(kubectl.commands()).To(Equal([]string{
+			"delete --ignore-not-found=true -f - -n default --dry-run=server"}))
+
+		result, err := resource.DecodeObject(stdout.String())
+		Expect(err).To(BeNil())
+		Expect(result.Name()).To(Equal("web"))
+	})
+
+	It("Should not replace CRDs with custom resources of other owners", func() {
+		kubectl.respond("widgets.example.com", widgets)
+		previous, _ := resource.DecodeObject(crd)
+
+		err := replaceObject(crd, "", previous, owner, nil, false,
+			&bytes.Buffer{}, nil, &KubectlConfig{})
+		Expect(err).NotTo(BeNil())
+		Expect(err.Error()).To(ContainSubstring("team-a/gadget"))
+		Expect(kubectl.commands()).To(Equal([]string{
+			"get widgets.example.com --all-namespaces -o json"}))
+
+		err = replaceObject(crd, "", previous, owner, nil, true,
+			&bytes.Buffer{}, nil, &KubectlConfig{})
+		Expect(err).To(BeNil())
+	})
+
+	It("Should not prune CRDs with custom resources of other owners", func() {
+		kubectl.respond("widgets.example.com", widgets)
+		labelled, _ := resource.DecodeObject(crd)
+		list, _ := json.Marshal(map[string]interface{}{"kind": "List",
+			"items": []interface{}{labelled}})
+		gvk := labelled.GroupVersionKind()
+		kubectl.respond(gvk.ResourceArg(), string(list))
+		tfResources := schema.NewSet(HashResource, []interface{}{})
+
+		err := pruneResources(owner, tfResources,
+			[]resource.GroupVersionKind{gvk}, nil, nil, false, &KubectlConfig{})
+		Expect(err).NotTo(BeNil())
+		Expect(kubectl.commands()).NotTo(ContainElement(ContainSubstring("delete")))
+
+		err = pruneResources(owner, tfResources,
+			[]resource.GroupVersionKind{gvk}, nil, nil, true, &KubectlConfig{})
+		Expect(err).To(BeNil())
+		Expect(kubectl.commands()).To(ContainElement(
+			"delete --ignore-not-found=true " + gvk.ResourceArg() +
+				"/widgets.example.com"))
+	})
+})
+
+var _ = Describe("Rollback", func() {
+
+	const configMap = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: default
+data:
+  level: debug`
+	const previousConfigMap = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: settings
+  namespace: default
+data:
+  level: info`
+	const service = `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: default`
+
+	var kubectl *fakeKubectl
+	var applied []appliedObject
+	var tfOldResources *schema.Set
+
+	BeforeEach(func() {
+		kubectl = installFakeKubectl()
+
+		// the ConfigMap was updated, then the Service created
+		live, _ := resource.DecodeObject(`{"apiVersion": "v1",
+			"kind": "ConfigMap", "metadata": {"name": "settings",
+			"namespace": "default", "uid": "1a2b",
+			"resourceVersion": "42"}, "data": {"level": "warn"}}`)
+		applied = []appliedObject{
+			{manifest: configMap, namespace: "default", previous: live},
+			{manifest: service, namespace: "default"},
+		}
+		tfOldResources = schema.NewSet(HashResource, []interface{}{
+			map[string]interface{}{
+				"selflink": "/api/v1/namespaces/default/configmaps/settings",
+				"uid":      "1a2b",
+				"content": base64.StdEncoding.EncodeToString(
+					[]byte(previousConfigMap)),
+			}})
+	})
+
+	AfterEach(func() {
+		kubectl.restore()
+	})
+
+	It("Should delete the created objects and restore the previous ones", func() {
+		err := rollbackResources(applied, tfOldResources, nil, nil,
+			&KubectlConfig{})
+		Expect(err).To(BeNil())
+		Expect(kubectl.commands()).To(Equal([]string{
+			"delete --ignore-not-found=true -f - -n default",
+			"apply -f - -n default"}))
+		Expect(kubectl.inputs()).To(Equal([]string{service,
+			previousConfigMap}))
+	})
+
+	It("Should restore the live object missing from the state", func() {
+		err := rollbackResources(applied,
+			schema.NewSet(HashResource, []interface{}{}), nil, nil,
+			&KubectlConfig{})
+		Expect(err).To(BeNil())
+		restored, err := resource.DecodeObject(kubectl.inputs()[1])
+		Expect(err).To(BeNil())
+		Expect(restored["data"]).To(Equal(
+			map[string]interface{}{"level": "warn"}))
+		Expect(restored.UID()).To(Equal(""))
+	})
+
+	It("Should stop at the first failure", func() {
+		kubectl.fail("delete", "Error from server (Forbidden): services "+
+			"\"web\" is forbidden")
+
+		err := rollbackResources(applied, tfOldResources, nil, nil,
+			&KubectlConfig{})
+		Expect(err).NotTo(BeNil())
+		Expect(err.Error()).To(ContainSubstring("Forbidden"))
+		Expect(kubectl.commands()).To(Equal([]string{
+			"delete --ignore-not-found=true -f - -n default"}))
+	})
+
+	It("Should not delete the created objects which are protected", func() {
+		protected := newProtectedObjects([]string{"Service"}, nil)
+
+		err := rollbackResources(applied, tfOldResources, nil, protected,
+			&KubectlConfig{})
+		Expect(err).To(BeNil())
+		Expect(kubectl.commands()).To(Equal([]string{
+			"apply -f - -n default"}))
+	})
+})
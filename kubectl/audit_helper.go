@@ -0,0 +1,138 @@
+package kubectl
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+)
+
+// A line of the audit log, recording an operation on a cluster object
+type auditRecord struct {
+	Timestamp        string `json:"timestamp"`
+	Operation        string `json:"operation"`
+	GroupVersionKind string `json:"gvk"`
+	Namespace        string `json:"namespace,omitempty"`
+	Name             string `json:"name"`
+	UID              string `json:"uid,omitempty"`
+	// type and name argument of the terraform resource operating on the
+	// object, which are not its terraform address
+	ResourceType string `json:"resource_type"`
+	ManifestName string `json:"manifest_name"`
+	DurationMs   int64  `json:"duration_ms"`
+	Outcome      string `json:"outcome"`
+	DryRun       string `json:"dry_run,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// Appends JSON lines to the audit log file. Records are written one at a
+// time, as they come from concurrent goroutines.
+type auditLog struct {
+	path     string
+	redactor *Redactor
+	mu       sync.Mutex
+}
+
+func newAuditLog(path string, redactor *Redactor) *auditLog {
+	if path == "" {
+		return nil
+	}
+	return &auditLog{path: path, redactor: redactor}
+}
+
+func (a *auditLog) write(record *auditRecord) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		log.Printf("[WARN] could not encode audit record: %s", err)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	file, err := os.OpenFile(a.path,
+		os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Printf("[WARN] could not open audit log %s: %s", a.path, err)
+		return
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		log.Printf("[WARN] could not write audit log %s: %s", a.path, err)
+	}
+}
+
+// Records the operations on the objects of a kubectl_manifest resource
+type manifestAudit struct {
+	log    *auditLog
+	name   string
+	dryRun string
+}
+
+func newManifestAudit(config *Config, name string) *manifestAudit {
+	if config.AuditLog == nil {
+		return nil
+	}
+	return &manifestAudit{log: config.AuditLog, name: name,
+		dryRun: config.DryRun}
+}
+
+// Records an operation started at the given time on the object, the
+// namespace being used when the object does not set one
+func (a *manifestAudit) record(operation string, obj resource.Object,
+	namespace, uid string, start time.Time, err error) {
+
+	if a == nil {
+		return
+	}
+	if obj == nil {
+		obj = resource.Object{}
+	}
+	if obj.Namespace() != "" {
+		namespace = obj.Namespace()
+	}
+	if uid == "" {
+		uid = obj.UID()
+	}
+
+	record := &auditRecord{
+		Timestamp:    start.UTC().Format(time.RFC3339Nano),
+		Operation:    operation,
+		Namespace:    namespace,
+		Name:         obj.Name(),
+		UID:          uid,
+		ResourceType: "kubectl_manifest",
+		ManifestName: a.name,
+		DurationMs:   int64(time.Since(start) / time.Millisecond),
+		Outcome:      "success",
+		DryRun:       a.dryRun,
+	}
+	if obj.Kind() != "" {
+		record.GroupVersionKind = obj.GroupVersionKind().String()
+	}
+	if err != nil {
+		record.Outcome = "failure"
+		record.Error = a.log.redactor.Redact(err.Error())
+	}
+	a.log.write(record)
+}
+
+// Decodes a manifest document for the audit log, ignoring malformed ones
+func auditObject(manifestResource string) resource.Object {
+	obj, _ := resource.DecodeObject(manifestResource)
+	return obj
+}
+
+// Decodes the document stored in the state for the audit log
+func auditStateObject(tfResource map[string]interface{}) resource.Object {
+	content, _ := tfResource["content"].(string)
+	decoded, err := base64.StdEncoding.DecodeString(content)
+	if err != nil {
+		return nil
+	}
+	return auditObject(string(decoded))
+}
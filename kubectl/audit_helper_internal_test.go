@@ -0,0 +1,105 @@
+package kubectl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Audit log", func() {
+
+	var dir string
+	var audit *manifestAudit
+
+	// decodes the lines of the audit log
+	records := func() []map[string]interface{} {
+		content, err := ioutil.ReadFile(filepath.Join(dir, "audit.jsonl"))
+		Expect(err).To(BeNil())
+		records := make([]map[string]interface{}, 0)
+		for _, line := range strings.Split(strings.TrimSuffix(
+			string(content), "\n"), "\n") {
+
+			record := make(map[string]interface{})
+			Expect(json.Unmarshal([]byte(line), &record)).To(Succeed())
+			records = append(records, record)
+		}
+		return records
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "audit")
+		Expect(err).To(BeNil())
+		audit = newManifestAudit(&Config{AuditLog: newAuditLog(
+			filepath.Join(dir, "audit.jsonl"), nil),
+			DryRun: DryRunServer}, "web")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("Should record operations as JSON lines", func() {
+		obj, _ := resource.DecodeObject(`{"apiVersion": "apps/v1",
+			"kind": "Deployment", "metadata": {"name": "web"}}`)
+		audit.record("apply", obj, "default", "5d3c0f7e", time.Now(), nil)
+		audit.record("delete", obj, "default", "", time.Now(),
+			errors.New("deployments.apps \"web\" is forbidden"))
+
+		Expect(records()).To(HaveLen(2))
+		applied, deleted := records()[0], records()[1]
+		Expect(applied).To(HaveKeyWithValue("operation", "apply"))
+		Expect(applied).To(HaveKeyWithValue("gvk", "apps/v1/Deployment"))
+		Expect(applied).To(HaveKeyWithValue("namespace", "default"))
+		Expect(applied).To(HaveKeyWithValue("name", "web"))
+		Expect(applied).To(HaveKeyWithValue("uid", "5d3c0f7e"))
+		Expect(applied).To(HaveKeyWithValue("resource_type", "kubectl_manifest"))
+		Expect(applied).To(HaveKeyWithValue("manifest_name", "web"))
+		Expect(applied).To(HaveKeyWithValue("outcome", "success"))
+		Expect(applied).To(HaveKeyWithValue("dry_run", DryRunServer))
+		Expect(applied).NotTo(HaveKey("error"))
+		Expect(deleted).To(HaveKeyWithValue("outcome", "failure"))
+		Expect(deleted).To(HaveKeyWithValue("error",
+			"deployments.apps \"web\" is forbidden"))
+	})
+
+	It("Should not interleave concurrent records", func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				obj, _ := resource.DecodeObject(fmt.Sprintf(`{"apiVersion": "v1",
+					"kind": "ConfigMap", "metadata": {"name": "settings-%d"}}`, i))
+				audit.record("apply", obj, "default", "", time.Now(), nil)
+			}(i)
+		}
+		wg.Wait()
+
+		names := make([]interface{}, 0)
+		for _, record := range records() {
+			names = append(names, record["name"])
+		}
+		Expect(names).To(HaveLen(50))
+		for i := 0; i < 50; i++ {
+			Expect(names).To(ContainElement(fmt.Sprintf("settings-%d", i)))
+		}
+	})
+
+	It("Should not record anything without an audit log", func() {
+		audit := newManifestAudit(&Config{}, "web")
+		Expect(audit).To(BeNil())
+		audit.record("apply", nil, "default", "", time.Now(), nil)
+	})
+})
@@ -0,0 +1,109 @@
+package kubectl
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/terraform/helper/hashcode"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func dataSourceAPIResources() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceAPIResourcesRead,
+
+		Schema: map[string]*schema.Schema{
+			"api_versions": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"resources": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"name": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"short_names": &schema.Schema{
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+						"api_version": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"group": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"kind": &schema.Schema{
+							Type:     schema.TypeString,
+							Computed: true,
+						},
+						"namespaced": &schema.Schema{
+							Type:     schema.TypeBool,
+							Computed: true,
+						},
+						"verbs": &schema.Schema{
+							Type:     schema.TypeList,
+							Computed: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// Lists the api versions and the resource types served by the cluster
+func dataSourceAPIResourcesRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+
+	kubectlCLIConfig, err := NewKubectlConfig(config)
+	if err != nil {
+		return fmt.Errorf("error while processing kubeconfig file: %s", err)
+	}
+	defer kubectlCLIConfig.Cleanup()
+
+	stdout := &bytes.Buffer{}
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	apiVersionsCommand := commandFactory.CreateAPIVersionsCommand(stdout)
+
+	if err := apiVersionsCommand.RunCommand(); err != nil {
+		return err
+	}
+	apiVersions := strings.Fields(stdout.String())
+
+	apiResources, err := discoverAPIResources(kubectlCLIConfig)
+	if err != nil {
+		return err
+	}
+
+	resources := make([]interface{}, 0, len(apiResources))
+	for _, apiResource := range apiResources {
+		resources = append(resources, map[string]interface{}{
+			"name":        apiResource.Name,
+			"short_names": apiResource.ShortNames,
+			"api_version": apiResource.APIVersion,
+			"group":       apiResource.Group(),
+			"kind":        apiResource.Kind,
+			"namespaced":  apiResource.Namespaced,
+			"verbs":       apiResource.Verbs,
+		})
+	}
+
+	if err := d.Set("api_versions", apiVersions); err != nil {
+		return err
+	}
+	if err := d.Set("resources", resources); err != nil {
+		return err
+	}
+	d.SetId(fmt.Sprintf("%d", hashcode.String(strings.Join(apiVersions, ","))))
+	return nil
+}
@@ -0,0 +1,142 @@
+package kubectl
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// marks the objects rendered as helm hooks
+const helmHookAnnotation = "helm.sh/hook"
+
+func dataSourceHelmTemplate() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceHelmTemplateRead,
+
+		Schema: map[string]*schema.Schema{
+			// local chart directory or packaged .tgz chart
+			"chart": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"release_name": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"namespace": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			// YAML values, later entries taking precedence
+			"values": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"set": &schema.Schema{
+				Type:     schema.TypeMap,
+				Optional: true,
+			},
+			"include_crds": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			"manifest": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"documents": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"hooks": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+// Renders the chart through `helm template` and splits the result into its
+// documents, the helm hooks being returned apart
+func dataSourceHelmTemplateRead(d *schema.ResourceData, m interface{}) error {
+	valueFiles := make([]string, 0)
+	defer func() {
+		for _, valueFile := range valueFiles {
+			deleteFile(valueFile)
+		}
+	}()
+	for _, values := range expandStringList(d.Get("values").([]interface{})) {
+		valueFile, err := createValuesFile(values)
+		if err != nil {
+			return fmt.Errorf("error while writing values file: %s", err)
+		}
+		valueFiles = append(valueFiles, valueFile)
+	}
+
+	stdout := &bytes.Buffer{}
+	commandFactory := &CLICommandFactory{
+		KubectlConfig: &KubectlConfig{Redactor: m.(*Config).Redactor}}
+	templateCommand := commandFactory.CreateHelmTemplateCommand(
+		d.Get("release_name").(string), d.Get("chart").(string),
+		d.Get("namespace").(string), valueFiles,
+		expandStringMap(d.Get("set").(map[string]interface{})),
+		d.Get("include_crds").(bool), stdout)
+
+	if err := templateCommand.RunCommand(); err != nil {
+		return fmt.Errorf("error while rendering chart %s: %s",
+			d.Get("chart").(string), err)
+	}
+
+	manifest := stdout.String()
+	rendered, err := resource.SplitYAMLDocument(manifest)
+	if err != nil {
+		return err
+	}
+	documents := make([]string, 0, len(rendered))
+	hooks := make([]string, 0)
+	for _, document := range rendered {
+		obj, err := resource.DecodeObject(document)
+		if err != nil {
+			return err
+		}
+		if _, ok := obj.Annotations()[helmHookAnnotation]; ok {
+			hooks = append(hooks, document)
+		} else {
+			documents = append(documents, document)
+		}
+	}
+
+	if err := d.Set("manifest", manifest); err != nil {
+		return err
+	}
+	if err := d.Set("documents", documents); err != nil {
+		return err
+	}
+	if err := d.Set("hooks", hooks); err != nil {
+		return err
+	}
+	d.SetId(fmt.Sprintf("%x", sha256.Sum256([]byte(manifest))))
+	return nil
+}
+
+func createValuesFile(values string) (string, error) {
+	valuesFile, err := ioutil.TempFile(os.TempDir(), "values_")
+	if err != nil {
+		return "", err
+	}
+	defer valuesFile.Close()
+	if _, err := valuesFile.WriteString(values); err != nil {
+		deleteFile(valuesFile.Name())
+		return "", err
+	}
+	return valuesFile.Name(), nil
+}
@@ -0,0 +1,63 @@
+package kubectl
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func dataSourceKustomizeDocuments() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceKustomizeDocumentsRead,
+
+		Schema: map[string]*schema.Schema{
+			"target": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"manifest": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"documents": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+		},
+	}
+}
+
+// Builds the kustomization and splits the result into its documents
+func dataSourceKustomizeDocumentsRead(d *schema.ResourceData,
+	m interface{}) error {
+
+	config := m.(*Config)
+
+	kubectlCLIConfig, err := NewKubectlConfig(config)
+	if err != nil {
+		return fmt.Errorf("error while processing kubeconfig file: %s", err)
+	}
+	defer kubectlCLIConfig.Cleanup()
+
+	manifest, err := buildKustomization(d.Get("target").(string),
+		kubectlCLIConfig)
+	if err != nil {
+		return err
+	}
+	documents, err := resource.SplitYAMLDocument(manifest)
+	if err != nil {
+		return err
+	}
+
+	if err := d.Set("manifest", manifest); err != nil {
+		return err
+	}
+	if err := d.Set("documents", documents); err != nil {
+		return err
+	}
+	d.SetId(fmt.Sprintf("%x", sha256.Sum256([]byte(manifest))))
+	return nil
+}
@@ -0,0 +1,116 @@
+package kubectl
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func dataSourceObject() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceObjectRead,
+
+		Schema: map[string]*schema.Schema{
+			"api_version": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"kind": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"name": &schema.Schema{
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"label_selector", "field_selector"},
+			},
+			"namespace": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"label_selector": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"field_selector": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			// name -> jsonpath expression evaluated against the object
+			"outputs": &schema.Schema{
+				Type:     schema.TypeMap,
+				Optional: true,
+			},
+			"output_values": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+			},
+			// the object, or the list of objects matching the selectors
+			"json": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+// Reads a live object by name, or the list of objects matching the label and
+// field selectors
+func dataSourceObjectRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+
+	kubectlCLIConfig, err := NewKubectlConfig(config)
+	if err != nil {
+		return fmt.Errorf("error while processing kubeconfig file: %s", err)
+	}
+	defer kubectlCLIConfig.Cleanup()
+
+	resourceType := resource.ResourceTypeArg(d.Get("api_version").(string),
+		d.Get("kind").(string))
+	namespace := d.Get("namespace").(string)
+	name := d.Get("name").(string)
+	labelSelector := d.Get("label_selector").(string)
+	fieldSelector := d.Get("field_selector").(string)
+
+	stdout := &bytes.Buffer{}
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+
+	var getCommand *CLICommand
+	if name != "" {
+		getCommand = commandFactory.CreateGetJSONByHandleCommand(
+			resourceType+"/"+name, namespace, stdout)
+	} else {
+		getCommand = commandFactory.CreateGetJSONBySelectorCommand(
+			resourceType, namespace, labelSelector, fieldSelector, stdout)
+	}
+	if err := getCommand.RunCommand(); err != nil {
+		return err
+	}
+	if strings.TrimSpace(stdout.String()) == "" {
+		return fmt.Errorf("%s/%s not found in namespace %q", resourceType,
+			name, namespace)
+	}
+
+	obj, err := resource.DecodeObject(stdout.String())
+	if err != nil {
+		return fmt.Errorf("decoding response: %v", err)
+	}
+	outputValues, err := evaluateOutputs(obj,
+		d.Get("outputs").(map[string]interface{}))
+	if err != nil {
+		return err
+	}
+
+	if err := d.Set("json", stdout.String()); err != nil {
+		return err
+	}
+	if err := d.Set("output_values", outputValues); err != nil {
+		return err
+	}
+	d.SetId(strings.Join([]string{namespace, resourceType, name,
+		labelSelector, fieldSelector}, "/"))
+	return nil
+}
@@ -0,0 +1,69 @@
+package kubectl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func dataSourceServerVersion() *schema.Resource {
+	return &schema.Resource{
+		Read: dataSourceServerVersionRead,
+
+		Schema: map[string]*schema.Schema{
+			"major": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"minor": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"git_version": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"platform": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func dataSourceServerVersionRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+
+	kubectlCLIConfig, err := NewKubectlConfig(config)
+	if err != nil {
+		return fmt.Errorf("error while processing kubeconfig file: %s", err)
+	}
+	defer kubectlCLIConfig.Cleanup()
+
+	stdout := &bytes.Buffer{}
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	versionCommand := commandFactory.CreateVersionCommand(stdout)
+
+	if err := versionCommand.RunCommand(); err != nil {
+		return err
+	}
+
+	var data resource.KubectlVersionResponse
+	if err := json.Unmarshal(stdout.Bytes(), &data); err != nil {
+		return fmt.Errorf("decoding response: %v", err)
+	}
+	if data.ServerVersion.GitVersion == "" {
+		return fmt.Errorf("could not parse server version from response %s",
+			stdout.String())
+	}
+
+	d.Set("major", data.ServerVersion.Major)
+	d.Set("minor", data.ServerVersion.Minor)
+	d.Set("git_version", data.ServerVersion.GitVersion)
+	d.Set("platform", data.ServerVersion.Platform)
+	d.SetId(data.ServerVersion.GitVersion)
+	return nil
+}
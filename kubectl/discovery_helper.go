@@ -0,0 +1,47 @@
+package kubectl
+
+import (
+	"bytes"
+	"log"
+	"strings"
+
+	"github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+)
+
+// Lists the resource types served by the cluster.
+//
+// `kubectl api-resources` exits with an error when any aggregated api is
+// unavailable, while still printing every resource it could discover: the
+// partial result is returned in that case.
+func discoverAPIResources(kubectlCLIConfig *KubectlConfig) (
+	[]resource.APIResource, error) {
+
+	stdout := &bytes.Buffer{}
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	apiResourcesCommand := commandFactory.CreateAPIResourcesCommand(stdout)
+
+	if err := apiResourcesCommand.RunCommand(); err != nil {
+		if strings.TrimSpace(stdout.String()) == "" {
+			return nil, err
+		}
+		log.Printf("[WARN] partial api discovery: %s", err)
+	}
+	return resource.ParseAPIResources(stdout.String()), nil
+}
+
+// Indexes whether a kind is namespaced, for every kind served by the
+// cluster. Kinds are keyed by group, as a custom resource may share the kind
+// of a core type with a different scope.
+func namespacedKinds(apiResources []resource.APIResource) map[string]bool {
+	namespaced := make(map[string]bool)
+	for _, apiResource := range apiResources {
+		namespaced[groupKind(apiResource.Group(), apiResource.Kind)] =
+			apiResource.Namespaced
+	}
+	return namespaced
+}
+
+// Keys a kind by its api group, i.e. `apps/Deployment` or `/ConfigMap`
+func groupKind(group, kind string) string {
+	return group + "/" + kind
+}
@@ -0,0 +1,111 @@
+package kubectl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/terraform/config"
+	"github.com/hashicorp/terraform/helper/schema"
+	"github.com/hashicorp/terraform/terraform"
+
+	. "github.com/onsi/gomega"
+)
+
+// Stands for kubectl, recording its commands and their standard input:
+//   - <command>.error makes the command fail with its content
+//   - apply rejects the documents marked as changing an immutable field
+//   - get lists the objects of a resource type from <type>.json
+const fakeKubectlScript = `#!/bin/sh
+dir=$(dirname "$0")
+echo "$*" >> "$dir/commands"
+manifest=$(cat)
+printf '%s\n---\n' "$manifest" >> "$dir/inputs"
+if [ -f "$dir/$1.error" ]; then
+  cat "$dir/$1.error" >&2
+  exit 1
+fi
+case "$1 $manifest" in
+apply*immutable-change*)
+  echo 'The Deployment "web" is invalid: spec.selector: Invalid value: ` +
+	`v1.LabelSelector{}: field is immutable' >&2
+  exit 1 ;;
+get*)
+  if [ -f "$dir/$2.json" ]; then
+    cat "$dir/$2.json"
+  else
+    echo '{"kind": "List", "items": []}'
+  fi ;;
+esac
+`
+
+// A fake kubectl, found first in the PATH until restored
+type fakeKubectl struct {
+	dir  string
+	path string
+}
+
+func installFakeKubectl() *fakeKubectl {
+	dir, err := ioutil.TempDir("", "kubectl")
+	Expect(err).To(BeNil())
+	Expect(ioutil.WriteFile(filepath.Join(dir, "kubectl"),
+		[]byte(fakeKubectlScript), 0755)).To(Succeed())
+
+	fake := &fakeKubectl{dir: dir, path: os.Getenv("PATH")}
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+fake.path)
+	return fake
+}
+
+func (f *fakeKubectl) restore() {
+	os.Setenv("PATH", f.path)
+	os.RemoveAll(f.dir)
+}
+
+// Lists the objects returned by `kubectl get <resourceType>`
+func (f *fakeKubectl) respond(resourceType, objects string) {
+	Expect(ioutil.WriteFile(filepath.Join(f.dir, resourceType+".json"),
+		[]byte(objects), 0644)).To(Succeed())
+}
+
+// Makes the given kubectl command fail
+func (f *fakeKubectl) fail(command, stderr string) {
+	Expect(ioutil.WriteFile(filepath.Join(f.dir, command+".error"),
+		[]byte(stderr), 0644)).To(Succeed())
+}
+
+// Lists the commands run so far, without the kubectl executable
+func (f *fakeKubectl) commands() []string {
+	content, err := ioutil.ReadFile(filepath.Join(f.dir, "commands"))
+	if os.IsNotExist(err) {
+		return []string{}
+	}
+	Expect(err).To(BeNil())
+	return strings.Split(strings.TrimSpace(string(content)), "\n")
+}
+
+// Lists the standard inputs of the commands run so far
+func (f *fakeKubectl) inputs() []string {
+	content, err := ioutil.ReadFile(filepath.Join(f.dir, "inputs"))
+	if os.IsNotExist(err) {
+		return []string{}
+	}
+	Expect(err).To(BeNil())
+	inputs := strings.Split(string(content), "\n---\n")
+	return inputs[:len(inputs)-1]
+}
+
+// Plans and applies a resource from its state and configuration, as
+// terraform does
+func applyResource(r *schema.Resource, state *terraform.InstanceState,
+	raw map[string]interface{}, meta interface{}) (*terraform.InstanceState,
+	error) {
+
+	rawConfig, err := config.NewRawConfig(raw)
+	Expect(err).To(BeNil())
+	diff, err := r.Diff(state, terraform.NewResourceConfig(rawConfig), meta)
+	if err != nil || diff == nil {
+		return state, err
+	}
+	return r.Apply(state, diff, meta)
+}
@@ -3,15 +3,68 @@ package kubectl
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os/exec"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
+const (
+	// objects are applied and deleted
+	DryRunNone = "none"
+	// objects are validated by kubectl only
+	DryRunClient = "client"
+	// objects are validated and defaulted by the API server, without being
+	// persisted
+	DryRunServer = "server"
+)
+
+func validateDryRun(v interface{}, k string) ([]string, []error) {
+	switch v.(string) {
+	case DryRunNone, DryRunClient, DryRunServer:
+		return nil, nil
+	}
+	return nil, []error{fmt.Errorf("%s must be one of %s, %s or %s, got %q",
+		k, DryRunNone, DryRunClient, DryRunServer, v.(string))}
+}
+
+// Fails the destroy of a resource once its dry-run delete succeeded, so that
+// Terraform keeps the resource, whose objects still exist, in its state
+func dryRunDestroyError(mode, outcome string) error {
+	return fmt.Errorf("dry_run is %s: %s, the resource is kept in the state",
+		mode, outcome)
+}
+
 type KubectlConfig struct {
 	Kubeconfig  string
 	Kubecontent string
 	Kubecontext string
-	toCleanup   bool
+	Redactor    *Redactor
+	// dry-run mode of the apply, patch and delete commands, if any
+	DryRun    string
+	toCleanup bool
+	// span of the current operation, parent of the command spans
+	span *Span
+}
+
+// Returns a copy of the config whose commands are traced as children of the
+// given span
+func (k *KubectlConfig) withSpan(span *Span) *KubectlConfig {
+	spanConfig := *k
+	spanConfig.toCleanup = false
+	spanConfig.span = span
+	return &spanConfig
+}
+
+// Returns a copy of the config whose apply, patch and delete commands are
+// run in the given dry-run mode
+func (k *KubectlConfig) withDryRun(mode string) *KubectlConfig {
+	dryRunConfig := *k
+	dryRunConfig.toCleanup = false
+	dryRunConfig.DryRun = mode
+	return &dryRunConfig
 }
 
 func (k *KubectlConfig) Cleanup() error {
@@ -63,7 +116,10 @@ func NewKubectlConfig(m interface{}) (*KubectlConfig, error) {
 		Kubeconfig:  kubeconfig,
 		Kubecontent: kubecontent,
 		Kubecontext: kubecontext,
+		Redactor:    m.(*Config).Redactor,
+		DryRun:      m.(*Config).DryRun,
 		toCleanup:   false,
+		span:        m.(*Config).span,
 	}
 
 	err = kubectlConfig.InitializeConfiguration()
@@ -72,23 +128,32 @@ func NewKubectlConfig(m interface{}) (*KubectlConfig, error) {
 
 type CLICommand struct {
 	*exec.Cmd
+	// masks the sensitive values of the command errors, if set
+	Redactor *Redactor
+	// parent span of the command span, if traced
+	Span *Span
 }
 
 func NewCLICommand(name string, args ...string) *CLICommand {
 
 	cmd := exec.Command(name, args...)
-	return &CLICommand{cmd}
+	return &CLICommand{Cmd: cmd}
 }
 
-func (c *CLICommand) RunCommand() error {
+func (c *CLICommand) RunCommand() (err error) {
+	span := c.Span.Child(commandName(c.Cmd.Args))
+	span.SetAttribute("command", c.Redactor.commandLine(c.Cmd.Args))
+	defer func() { span.End(err) }()
+
 	stderr := &bytes.Buffer{}
 	c.Cmd.Stderr = stderr
 	if err := c.Cmd.Run(); err != nil {
-		cmdStr := c.Cmd.Path + " " + strings.Join(c.Cmd.Args, " ")
+		cmdStr := c.Cmd.Path + " " + c.Redactor.commandLine(c.Cmd.Args)
 		if stderr.Len() == 0 {
 			return fmt.Errorf("%s: %v", cmdStr, err)
 		}
-		return fmt.Errorf("%s %v: %s", cmdStr, err, stderr.Bytes())
+		return fmt.Errorf("%s %v: %s", cmdStr, err,
+			c.Redactor.Redact(stderr.String()))
 	}
 	return nil
 }
@@ -97,6 +162,31 @@ type CLICommandFactory struct {
 	KubectlConfig *KubectlConfig
 }
 
+func (c *CLICommandFactory) redactor() *Redactor {
+	if c.KubectlConfig == nil {
+		return nil
+	}
+	return c.KubectlConfig.Redactor
+}
+
+// Builds a command whose errors are redacted
+func (c *CLICommandFactory) newCommand(name string,
+	args ...string) *CLICommand {
+
+	command := NewCLICommand(name, args...)
+	command.Redactor = c.redactor()
+	if c.KubectlConfig != nil {
+		command.Span = c.KubectlConfig.span
+	}
+	return command
+}
+
+// Feeds a manifest to a command, registering its Secret values for redaction
+func (c *CLICommandFactory) manifestInput(manifest string) io.Reader {
+	c.redactor().AddManifest(manifest)
+	return strings.NewReader(manifest)
+}
+
 func (c *CLICommandFactory) CreateGetByHandleCommand(
 	resourceHandle, namespace string, stdout *bytes.Buffer) *CLICommand {
 
@@ -106,7 +196,43 @@ func (c *CLICommandFactory) CreateGetByHandleCommand(
 	}
 
 	args = c.KubectlConfig.RenderArgs(args...)
-	getCommand := NewCLICommand("kubectl", args...)
+	getCommand := c.newCommand("kubectl", args...)
+	getCommand.Stdout = stdout
+	return getCommand
+}
+
+func (c *CLICommandFactory) CreateGetJSONByHandleCommand(
+	resourceHandle, namespace string, stdout *bytes.Buffer) *CLICommand {
+
+	args := []string{"get", "--ignore-not-found=true", resourceHandle,
+		"-o", "json"}
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+
+	args = c.KubectlConfig.RenderArgs(args...)
+	getCommand := c.newCommand("kubectl", args...)
+	getCommand.Stdout = stdout
+	return getCommand
+}
+
+func (c *CLICommandFactory) CreateGetJSONBySelectorCommand(
+	resourceType, namespace, labelSelector, fieldSelector string,
+	stdout *bytes.Buffer) *CLICommand {
+
+	args := []string{"get", resourceType, "-o", "json"}
+	if labelSelector != "" {
+		args = append(args, "-l", labelSelector)
+	}
+	if fieldSelector != "" {
+		args = append(args, "--field-selector", fieldSelector)
+	}
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+
+	args = c.KubectlConfig.RenderArgs(args...)
+	getCommand := c.newCommand("kubectl", args...)
 	getCommand.Stdout = stdout
 	return getCommand
 }
@@ -118,8 +244,96 @@ func (c *CLICommandFactory) CreateGetByManifestCommand(
 	if namespace != "" {
 		args = append(args, "-n", namespace)
 	}
-	getCommand := NewCLICommand("kubectl", args...)
-	getCommand.Stdin = strings.NewReader(resourceManifest)
+	getCommand := c.newCommand("kubectl", args...)
+	getCommand.Stdin = c.manifestInput(resourceManifest)
+	getCommand.Stdout = stdout
+	return getCommand
+}
+
+func (c *CLICommandFactory) CreateGetIfExistsByManifestCommand(
+	resourceManifest, namespace string, stdout *bytes.Buffer) *CLICommand {
+
+	args := c.KubectlConfig.RenderArgs("get", "--ignore-not-found=true",
+		"-f", "-", "-o", "json")
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	getCommand := c.newCommand("kubectl", args...)
+	getCommand.Stdin = c.manifestInput(resourceManifest)
+	getCommand.Stdout = stdout
+	return getCommand
+}
+
+func (c *CLICommandFactory) CreateGetAllByLabelCommand(
+	resourceType, label string, stdout *bytes.Buffer) *CLICommand {
+
+	args := c.KubectlConfig.RenderArgs("get", resourceType, "--all-namespaces",
+		"-l", label, "-o", "json")
+	getCommand := c.newCommand("kubectl", args...)
+	getCommand.Stdout = stdout
+	return getCommand
+}
+
+// Waits for a condition on an object, or on the objects matching a label
+// selector, printing out the matched objects. kubectl watches the objects
+// until the condition is met or the timeout expires.
+func (c *CLICommandFactory) CreateWaitCommand(
+	resourceArg, selector, namespace, condition string, timeout time.Duration,
+	stdout *bytes.Buffer) *CLICommand {
+
+	args := []string{"wait", resourceArg}
+	if selector != "" {
+		args = append(args, "-l", selector)
+	}
+	args = append(args, "--for", condition, "--timeout", timeout.String(),
+		"-o", "json")
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+
+	args = c.KubectlConfig.RenderArgs(args...)
+	waitCommand := c.newCommand("kubectl", args...)
+	waitCommand.Stdout = stdout
+	return waitCommand
+}
+
+// Waits for the rollout of a workload to complete
+func (c *CLICommandFactory) CreateRolloutStatusCommand(
+	resourceHandle, namespace string, timeout time.Duration) *CLICommand {
+
+	args := []string{"rollout", "status", resourceHandle, "--timeout",
+		timeout.String()}
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+
+	args = c.KubectlConfig.RenderArgs(args...)
+	return c.newCommand("kubectl", args...)
+}
+
+// Prints out the last lines of the logs of every container of a pod
+func (c *CLICommandFactory) CreateLogsCommand(
+	resourceHandle, namespace string, tail int,
+	stdout *bytes.Buffer) *CLICommand {
+
+	args := []string{"logs", resourceHandle, "--all-containers", "--tail",
+		strconv.Itoa(tail)}
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+
+	args = c.KubectlConfig.RenderArgs(args...)
+	logsCommand := c.newCommand("kubectl", args...)
+	logsCommand.Stdout = stdout
+	return logsCommand
+}
+
+func (c *CLICommandFactory) CreateGetAllCommand(
+	resourceType string, stdout *bytes.Buffer) *CLICommand {
+
+	args := c.KubectlConfig.RenderArgs("get", resourceType, "--all-namespaces",
+		"-o", "json")
+	getCommand := c.newCommand("kubectl", args...)
 	getCommand.Stdout = stdout
 	return getCommand
 }
@@ -131,11 +345,83 @@ func (c *CLICommandFactory) CreateApplyManifestCommand(
 	if namespace != "" {
 		args = append(args, "-n", namespace)
 	}
-	applyCommand := NewCLICommand("kubectl", args...)
-	applyCommand.Stdin = strings.NewReader(manifestResource)
+	if c.KubectlConfig.DryRun != "" {
+		// the object that would be applied is printed out, as it can not
+		// be fetched afterwards
+		args = append(args, c.dryRunArg(), "-o", "json")
+	}
+	applyCommand := c.newCommand("kubectl", args...)
+	applyCommand.Stdin = c.manifestInput(manifestResource)
 	return applyCommand
 }
 
+func (c *CLICommandFactory) CreateAPIResourcesCommand(
+	stdout *bytes.Buffer) *CLICommand {
+
+	args := c.KubectlConfig.RenderArgs("api-resources", "-o", "wide")
+	apiResourcesCommand := c.newCommand("kubectl", args...)
+	apiResourcesCommand.Stdout = stdout
+	return apiResourcesCommand
+}
+
+func (c *CLICommandFactory) CreateAPIVersionsCommand(
+	stdout *bytes.Buffer) *CLICommand {
+
+	args := c.KubectlConfig.RenderArgs("api-versions")
+	apiVersionsCommand := c.newCommand("kubectl", args...)
+	apiVersionsCommand.Stdout = stdout
+	return apiVersionsCommand
+}
+
+func (c *CLICommandFactory) CreateVersionCommand(
+	stdout *bytes.Buffer) *CLICommand {
+
+	args := c.KubectlConfig.RenderArgs("version", "-o", "json")
+	versionCommand := c.newCommand("kubectl", args...)
+	versionCommand.Stdout = stdout
+	return versionCommand
+}
+
+func (c *CLICommandFactory) CreateKustomizeCommand(
+	path string, stdout *bytes.Buffer) *CLICommand {
+
+	args := c.KubectlConfig.RenderArgs("kustomize", path)
+	kustomizeCommand := c.newCommand("kubectl", args...)
+	kustomizeCommand.Stdout = stdout
+	return kustomizeCommand
+}
+
+// Renders a chart through `helm template`, which does not need access to the
+// cluster
+func (c *CLICommandFactory) CreateHelmTemplateCommand(
+	releaseName, chart, namespace string, valueFiles []string,
+	set map[string]string, includeCRDs bool,
+	stdout *bytes.Buffer) *CLICommand {
+
+	args := []string{"template", releaseName, chart}
+	if namespace != "" {
+		args = append(args, "--namespace", namespace)
+	}
+	if includeCRDs {
+		args = append(args, "--include-crds")
+	}
+	for _, valueFile := range valueFiles {
+		args = append(args, "--values", valueFile)
+	}
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, "--set", key+"="+set[key])
+	}
+
+	templateCommand := c.newCommand("helm", args...)
+	templateCommand.Stdout = stdout
+	return templateCommand
+}
+
 func (c *CLICommandFactory) CreateDeleteByHandleCommand(
 	resourceHandle, namespace string) *CLICommand {
 
@@ -145,8 +431,48 @@ func (c *CLICommandFactory) CreateDeleteByHandleCommand(
 	if namespace != "" {
 		args = append(args, "-n", namespace)
 	}
+	if c.KubectlConfig.DryRun != "" {
+		args = append(args, c.dryRunArg())
+	}
 
-	deleteCommand := NewCLICommand("kubectl", args...)
+	deleteCommand := c.newCommand("kubectl", args...)
 	deleteCommand.Stdin = strings.NewReader(resourceHandle)
 	return deleteCommand
 }
+
+func (c *CLICommandFactory) CreateDeleteByManifestCommand(
+	manifestResource, namespace string) *CLICommand {
+
+	args := c.KubectlConfig.RenderArgs("delete", "--ignore-not-found=true",
+		"-f", "-")
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	if c.KubectlConfig.DryRun != "" {
+		args = append(args, c.dryRunArg())
+	}
+	deleteCommand := c.newCommand("kubectl", args...)
+	deleteCommand.Stdin = c.manifestInput(manifestResource)
+	return deleteCommand
+}
+
+func (c *CLICommandFactory) CreatePatchByHandleCommand(
+	resourceHandle, namespace, patchType, patch string) *CLICommand {
+
+	if strings.HasPrefix(strings.ToLower(resourceHandle), "secret") {
+		c.redactor().AddPatch(patch)
+	}
+	args := c.KubectlConfig.RenderArgs("patch", resourceHandle,
+		"--type", patchType, "-p", patch)
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	if c.KubectlConfig.DryRun != "" {
+		args = append(args, c.dryRunArg())
+	}
+	return c.newCommand("kubectl", args...)
+}
+
+func (c *CLICommandFactory) dryRunArg() string {
+	return "--dry-run=" + c.KubectlConfig.DryRun
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -101,10 +102,25 @@ var _ = Describe("CLICommandFactory", func() {
 		Context("When kubeconfig parameter is set", func() {
 
 			expectedGetByHandle := "kubectl --kubeconfig /home/user/.kube/config get --ignore-not-found=true /v2/myresourceHandle -n test"
+			expectedGetJSONByHandle := "kubectl --kubeconfig /home/user/.kube/config get --ignore-not-found=true /v2/myresourceHandle -o json -n test"
 			expectedGetByManifest := "kubectl --kubeconfig /home/user/.kube/config get -f - -o json -n test"
+			expectedGetIfExistsByManifest := "kubectl --kubeconfig /home/user/.kube/config get --ignore-not-found=true -f - -o json -n test"
+			expectedGetAllByLabel := "kubectl --kubeconfig /home/user/.kube/config get deployment.v1.apps --all-namespaces -l app=test -o json"
+			expectedGetAll := "kubectl --kubeconfig /home/user/.kube/config get certificates.cert-manager.io --all-namespaces -o json"
+			expectedDeleteByManifest := "kubectl --kubeconfig /home/user/.kube/config delete --ignore-not-found=true -f - -n test"
+			expectedPatchByHandle := "kubectl --kubeconfig /home/user/.kube/config patch deployment/coredns --type merge -p {\"spec\":{\"replicas\":3}} -n kube-system"
+			expectedGetJSONBySelector := "kubectl --kubeconfig /home/user/.kube/config get service -o json -l app=nginx --field-selector metadata.name=nginx -n test"
 			expectedStdin := "---\napiVersion: v1\nkind: Namespace\n  metadata:\n  name: acceptance-test"
 			expectedDeleteByHandle := "kubectl --kubeconfig /home/user/.kube/config delete --ignore-not-found=true /v2/myResource -n test"
 			expectedApplyManifest := "kubectl --kubeconfig /home/user/.kube/config apply -f - -n test"
+			expectedAPIResources := "kubectl --kubeconfig /home/user/.kube/config api-resources -o wide"
+			expectedAPIVersions := "kubectl --kubeconfig /home/user/.kube/config api-versions"
+			expectedVersion := "kubectl --kubeconfig /home/user/.kube/config version -o json"
+			expectedKustomize := "kubectl --kubeconfig /home/user/.kube/config kustomize overlays/production"
+			expectedWait := "kubectl --kubeconfig /home/user/.kube/config wait certificate.v1.cert-manager.io -l app=web --for condition=Ready --timeout 2m0s -o json -n test"
+			expectedRolloutStatus := "kubectl --kubeconfig /home/user/.kube/config rollout status deployment.v1.apps/web --timeout 5m0s -n test"
+			expectedLogs := "kubectl --kubeconfig /home/user/.kube/config logs pod/migrate-a1b2c3d4-x7k2p --all-containers --tail 20 -n test"
+			expectedHelmTemplate := "helm template ingress charts/ingress-nginx --namespace ingress --include-crds --values /tmp/values.yaml --set controller.replicaCount=2 --set rbac.create=true"
 			var (
 				filepath       string
 				config         *Config
@@ -132,6 +148,26 @@ var _ = Describe("CLICommandFactory", func() {
 				Expect(resultingCommand).To(Equal(expectedGetByHandle))
 			})
 
+			It("Should create a valid json get by handle command", func() {
+				stdout := &bytes.Buffer{}
+				getCommand := commandFactory.CreateGetJSONByHandleCommand(
+					"/v2/myresourceHandle", "test", stdout)
+
+				resultingCommand := strings.Join(getCommand.Args, " ")
+
+				Expect(resultingCommand).To(Equal(expectedGetJSONByHandle))
+			})
+
+			It("Should create a valid json get by selector command", func() {
+				stdout := &bytes.Buffer{}
+				getCommand := commandFactory.CreateGetJSONBySelectorCommand(
+					"service", "test", "app=nginx", "metadata.name=nginx", stdout)
+
+				resultingCommand := strings.Join(getCommand.Args, " ")
+
+				Expect(resultingCommand).To(Equal(expectedGetJSONBySelector))
+			})
+
 			It("Should create a valid get by manifest command", func() {
 				stdout := &bytes.Buffer{}
 				getCommand := commandFactory.CreateGetByManifestCommand(
@@ -147,6 +183,30 @@ var _ = Describe("CLICommandFactory", func() {
 
 			})
 
+			It("Should create a valid get if exists by manifest command", func() {
+				stdout := &bytes.Buffer{}
+				getCommand := commandFactory.CreateGetIfExistsByManifestCommand(
+					expectedStdin, "test", stdout)
+
+				resultingCommand := strings.Join(getCommand.Args, " ")
+
+				buf := new(bytes.Buffer)
+				buf.ReadFrom(getCommand.Stdin)
+
+				Expect(resultingCommand).To(Equal(expectedGetIfExistsByManifest))
+				Expect(buf.String()).To(Equal(expectedStdin))
+			})
+
+			It("Should create a valid get all by label command", func() {
+				stdout := &bytes.Buffer{}
+				getCommand := commandFactory.CreateGetAllByLabelCommand(
+					"deployment.v1.apps", "app=test", stdout)
+
+				resultingCommand := strings.Join(getCommand.Args, " ")
+
+				Expect(resultingCommand).To(Equal(expectedGetAllByLabel))
+			})
+
 			It("Should create a valid delete by handle command", func() {
 				deleteCommand := commandFactory.CreateDeleteByHandleCommand(
 					"/v2/myResource", "test")
@@ -155,6 +215,27 @@ var _ = Describe("CLICommandFactory", func() {
 				Expect(resultingCommand).To(Equal(expectedDeleteByHandle))
 			})
 
+			It("Should create a valid delete by manifest command", func() {
+				deleteCommand := commandFactory.CreateDeleteByManifestCommand(
+					expectedStdin, "test")
+				resultingCommand := strings.Join(deleteCommand.Args, " ")
+
+				buf := new(bytes.Buffer)
+				buf.ReadFrom(deleteCommand.Stdin)
+
+				Expect(resultingCommand).To(Equal(expectedDeleteByManifest))
+				Expect(buf.String()).To(Equal(expectedStdin))
+			})
+
+			It("Should create a valid patch by handle command", func() {
+				patchCommand := commandFactory.CreatePatchByHandleCommand(
+					"deployment/coredns", "kube-system", "merge",
+					`{"spec":{"replicas":3}}`)
+				resultingCommand := strings.Join(patchCommand.Args, " ")
+
+				Expect(resultingCommand).To(Equal(expectedPatchByHandle))
+			})
+
 			It("Should create a valid apply command", func() {
 				applyCommand := commandFactory.CreateApplyManifestCommand(
 					expectedStdin, "test")
@@ -167,6 +248,138 @@ var _ = Describe("CLICommandFactory", func() {
 				Expect(resultingCommand).To(Equal(expectedApplyManifest))
 				Expect(buf.String()).To(Equal(expectedStdin))
 			})
+
+			It("Should create a valid api resources command", func() {
+				stdout := &bytes.Buffer{}
+				apiResourcesCommand := commandFactory.CreateAPIResourcesCommand(
+					stdout)
+
+				resultingCommand := strings.Join(apiResourcesCommand.Args, " ")
+
+				Expect(resultingCommand).To(Equal(expectedAPIResources))
+			})
+
+			It("Should create a valid api versions command", func() {
+				stdout := &bytes.Buffer{}
+				apiVersionsCommand := commandFactory.CreateAPIVersionsCommand(
+					stdout)
+
+				resultingCommand := strings.Join(apiVersionsCommand.Args, " ")
+
+				Expect(resultingCommand).To(Equal(expectedAPIVersions))
+			})
+
+			It("Should create a valid version command", func() {
+				stdout := &bytes.Buffer{}
+				versionCommand := commandFactory.CreateVersionCommand(stdout)
+
+				resultingCommand := strings.Join(versionCommand.Args, " ")
+
+				Expect(resultingCommand).To(Equal(expectedVersion))
+			})
+
+			It("Should create a valid kustomize command", func() {
+				stdout := &bytes.Buffer{}
+				kustomizeCommand := commandFactory.CreateKustomizeCommand(
+					"overlays/production", stdout)
+
+				resultingCommand := strings.Join(kustomizeCommand.Args, " ")
+
+				Expect(resultingCommand).To(Equal(expectedKustomize))
+			})
+
+			It("Should create a valid wait command", func() {
+				stdout := &bytes.Buffer{}
+				waitCommand := commandFactory.CreateWaitCommand(
+					"certificate.v1.cert-manager.io", "app=web", "test",
+					"condition=Ready", 2*time.Minute, stdout)
+
+				resultingCommand := strings.Join(waitCommand.Args, " ")
+
+				Expect(resultingCommand).To(Equal(expectedWait))
+			})
+
+			It("Should create a valid rollout status command", func() {
+				statusCommand := commandFactory.CreateRolloutStatusCommand(
+					"deployment.v1.apps/web", "test", 5*time.Minute)
+
+				resultingCommand := strings.Join(statusCommand.Args, " ")
+
+				Expect(resultingCommand).To(Equal(expectedRolloutStatus))
+			})
+
+			It("Should create a valid logs command", func() {
+				stdout := &bytes.Buffer{}
+				logsCommand := commandFactory.CreateLogsCommand(
+					"pod/migrate-a1b2c3d4-x7k2p", "test", 20, stdout)
+
+				resultingCommand := strings.Join(logsCommand.Args, " ")
+
+				Expect(resultingCommand).To(Equal(expectedLogs))
+			})
+
+			It("Should create a valid get all command", func() {
+				stdout := &bytes.Buffer{}
+				getCommand := commandFactory.CreateGetAllCommand(
+					"certificates.cert-manager.io", stdout)
+
+				resultingCommand := strings.Join(getCommand.Args, " ")
+
+				Expect(resultingCommand).To(Equal(expectedGetAll))
+			})
+
+			It("Should create a valid helm template command", func() {
+				stdout := &bytes.Buffer{}
+				templateCommand := commandFactory.CreateHelmTemplateCommand(
+					"ingress", "charts/ingress-nginx", "ingress",
+					[]string{"/tmp/values.yaml"}, map[string]string{
+						"rbac.create":             "true",
+						"controller.replicaCount": "2",
+					}, true, stdout)
+
+				resultingCommand := strings.Join(templateCommand.Args, " ")
+
+				Expect(resultingCommand).To(Equal(expectedHelmTemplate))
+			})
+		})
+
+		Context("When dry run parameter is set", func() {
+
+			expectedApplyManifest := "kubectl apply -f - -n test --dry-run=server -o json"
+			expectedDeleteByHandle := "kubectl delete --ignore-not-found=true /v2/myResource -n test --dry-run=server"
+			expectedDeleteByManifest := "kubectl delete --ignore-not-found=true -f - -n test --dry-run=server"
+			expectedPatchByHandle := "kubectl patch deployment/coredns --type merge -p {\"spec\":{\"replicas\":3}} -n kube-system --dry-run=server"
+
+			var commandFactory *CLICommandFactory
+
+			BeforeEach(func() {
+				kubectlConfig, err := NewKubectlConfig(
+					&Config{DryRun: DryRunServer})
+				commandFactory = &CLICommandFactory{KubectlConfig: kubectlConfig}
+
+				Expect(err).To(BeNil())
+			})
+
+			It("Should create dry-run apply, delete and patch commands", func() {
+				applyCommand := commandFactory.CreateApplyManifestCommand(
+					"", "test")
+				deleteByHandleCommand := commandFactory.CreateDeleteByHandleCommand(
+					"/v2/myResource", "test")
+				deleteByManifestCommand := commandFactory.CreateDeleteByManifestCommand(
+					"", "test")
+				patchCommand := commandFactory.CreatePatchByHandleCommand(
+					"deployment/coredns", "kube-system", "merge",
+					`{"spec":{"replicas":3}}`)
+
+				Expect(strings.Join(applyCommand.Args, " ")).To(
+					Equal(expectedApplyManifest))
+				Expect(strings.Join(deleteByHandleCommand.Args, " ")).To(
+					Equal(expectedDeleteByHandle))
+				Expect(strings.Join(deleteByManifestCommand.Args, " ")).To(
+					Equal(expectedDeleteByManifest))
+				Expect(strings.Join(patchCommand.Args, " ")).To(
+					Equal(expectedPatchByHandle))
+			})
 		})
 
 	})
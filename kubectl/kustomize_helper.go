@@ -0,0 +1,157 @@
+package kubectl
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+var kustomizationFiles = []string{
+	"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// Builds a kustomization through `kubectl kustomize`
+func buildKustomization(path string,
+	kubectlCLIConfig *KubectlConfig) (string, error) {
+
+	stdout := &bytes.Buffer{}
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	kustomizeCommand := commandFactory.CreateKustomizeCommand(path, stdout)
+
+	if err := kustomizeCommand.RunCommand(); err != nil {
+		return "", fmt.Errorf("error while building kustomization %s: %s",
+			path, err)
+	}
+	return stdout.String(), nil
+}
+
+// Hashes the build inputs of a kustomization: every file below its
+// directory, along with the local bases, components and resources it
+// references outside of it. Remote bases are not hashed. Files are hashed by
+// their path relative to the kustomization, so the hash does not depend on
+// where the configuration is checked out.
+func hashKustomization(path string) (string, error) {
+	root, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	if err := hashKustomizationInputs(root, root, hash,
+		make(map[string]bool)); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil)), nil
+}
+
+func hashKustomizationInputs(root, path string, hash io.Writer,
+	visited map[string]bool) error {
+
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if visited[path] {
+		return nil
+	}
+	visited[path] = true
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return hashFile(root, path, hash)
+	}
+
+	files := make([]string, 0)
+	err = filepath.Walk(path, func(file string, info os.FileInfo,
+		err error) error {
+
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			files = append(files, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		visited[file] = true
+		if err := hashFile(root, file, hash); err != nil {
+			return err
+		}
+	}
+
+	references, err := kustomizationReferences(path)
+	if err != nil {
+		return err
+	}
+	for _, reference := range references {
+		if isRemoteReference(reference) {
+			continue
+		}
+		if err := hashKustomizationInputs(root,
+			filepath.Join(path, reference), hash, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hashFile(root, path string, hash io.Writer) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	name, err := filepath.Rel(root, path)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(hash, "%s\x00%d\x00", filepath.ToSlash(name), len(content))
+	_, err = hash.Write(content)
+	return err
+}
+
+// Lists the bases, components and resources of the kustomization found in
+// a directory
+func kustomizationReferences(dir string) ([]string, error) {
+	for _, name := range kustomizationFiles {
+		content, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		var kustomization struct {
+			Resources  []string `json:"resources"`
+			Bases      []string `json:"bases"`
+			Components []string `json:"components"`
+		}
+		if err := yaml.Unmarshal(content, &kustomization); err != nil {
+			return nil, fmt.Errorf("error while parsing %s: %s",
+				filepath.Join(dir, name), err)
+		}
+		references := append(kustomization.Resources, kustomization.Bases...)
+		return append(references, kustomization.Components...), nil
+	}
+	return nil, nil
+}
+
+func isRemoteReference(reference string) bool {
+	return strings.Contains(reference, "://") ||
+		strings.HasPrefix(reference, "git@") ||
+		strings.HasPrefix(reference, "github.com/")
+}
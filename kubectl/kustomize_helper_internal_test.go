@@ -0,0 +1,56 @@
+package kubectl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Kustomization hash", func() {
+
+	// writes a kustomization with an overlay referencing a base
+	checkout := func(content string) string {
+		dir, err := ioutil.TempDir("", "kustomization")
+		Expect(err).To(BeNil())
+		files := map[string]string{
+			"base/kustomization.yaml":    "resources:\n- deployment.yaml\n",
+			"base/deployment.yaml":       content,
+			"overlay/kustomization.yaml": "bases:\n- ../base\n",
+		}
+		for name, content := range files {
+			path := filepath.Join(dir, name)
+			Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(path, []byte(content), 0644)).To(Succeed())
+		}
+		return dir
+	}
+
+	It("Should not depend on the location of the kustomization", func() {
+		first, second := checkout("kind: Deployment\n"),
+			checkout("kind: Deployment\n")
+		defer os.RemoveAll(first)
+		defer os.RemoveAll(second)
+
+		firstHash, err := hashKustomization(filepath.Join(first, "overlay"))
+		Expect(err).To(BeNil())
+		secondHash, err := hashKustomization(filepath.Join(second, "overlay"))
+		Expect(err).To(BeNil())
+		Expect(firstHash).To(Equal(secondHash))
+	})
+
+	It("Should change with the content of the bases", func() {
+		first, second := checkout("kind: Deployment\n"),
+			checkout("kind: StatefulSet\n")
+		defer os.RemoveAll(first)
+		defer os.RemoveAll(second)
+
+		firstHash, err := hashKustomization(filepath.Join(first, "overlay"))
+		Expect(err).To(BeNil())
+		secondHash, err := hashKustomization(filepath.Join(second, "overlay"))
+		Expect(err).To(BeNil())
+		Expect(firstHash).NotTo(Equal(secondHash))
+	})
+})
@@ -0,0 +1,88 @@
+package kubectl
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+)
+
+// interval between two evaluations of outputs which are not available yet
+const outputsPollInterval = 5 * time.Second
+
+// Evaluates every jsonpath expression of the outputs map against the object
+func evaluateOutputs(obj resource.Object, outputs map[string]interface{}) (
+	map[string]string, error) {
+
+	values := make(map[string]string, len(outputs))
+	for name, expression := range outputs {
+		value, err := obj.EvaluateJSONPath(expression.(string))
+		if err != nil {
+			return nil, fmt.Errorf("invalid jsonpath for output %q: %v",
+				name, err)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// Evaluates the outputs of a kubectl_manifest against the list of the applied
+// objects, in the order of the manifest documents.
+//
+// When wait is set, the objects are fetched again until every output has a
+// value, or the timeout expires. In dry-run mode, the outputs are evaluated
+// once against the objects returned by the dry-run.
+func manifestOutputs(applied []appliedObject, outputs map[string]interface{},
+	wait bool, timeout time.Duration, kubectlCLIConfig *KubectlConfig) (
+	values map[string]string, err error) {
+
+	if len(outputs) == 0 {
+		return map[string]string{}, nil
+	}
+
+	span := kubectlCLIConfig.span.Child("outputs")
+	defer func() { span.End(err) }()
+	kubectlCLIConfig = kubectlCLIConfig.withSpan(span)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		items := make([]interface{}, 0, len(applied))
+		for _, object := range applied {
+			if object.result != nil {
+				items = append(items, map[string]interface{}(object.result))
+				continue
+			}
+			live, err := getLiveObject(object.manifest, object.namespace,
+				kubectlCLIConfig)
+			if err != nil {
+				return nil, err
+			}
+			if live != nil {
+				items = append(items, map[string]interface{}(live))
+			}
+		}
+
+		list := resource.Object{"kind": "List", "items": items}
+		values, err := evaluateOutputs(list, outputs)
+		if err != nil {
+			return nil, err
+		}
+
+		missing := make([]string, 0)
+		for name, value := range values {
+			if value == "" {
+				missing = append(missing, name)
+			}
+		}
+		if !wait || len(missing) == 0 || kubectlCLIConfig.DryRun != "" {
+			return values, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timeout while waiting for outputs %v",
+				missing)
+		}
+		log.Printf("[DEBUG] waiting for outputs %v", missing)
+		time.Sleep(outputsPollInterval)
+	}
+}
@@ -0,0 +1,128 @@
+package kubectl
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+const (
+	// identifies the kubectl_manifest resource owning an object
+	ownerIDAnnotation = "kubectl.terraform.io/owner-id"
+	// human readable name of the owner, only used in error messages
+	ownerNameAnnotation = "kubectl.terraform.io/owner"
+	// selects every object applied by a kubectl_manifest resource, even the
+	// ones missing from its state
+	manifestLabel = "kubectl.terraform.io/manifest"
+)
+
+// The terraform resource owning the applied objects
+type manifestOwner struct {
+	id       string
+	name     string
+	takeover bool
+}
+
+// Builds the owner of a kubectl_manifest resource, deriving its owner id
+// from its name when the resource does not have one yet.
+func newManifestOwner(d *schema.ResourceData) (*manifestOwner, error) {
+	id := d.Get("owner_id").(string)
+	if id == "" {
+		id = manifestOwnerID(d.Get("name").(string))
+		if err := d.Set("owner_id", id); err != nil {
+			return nil, err
+		}
+	}
+	return &manifestOwner{
+		id:       id,
+		name:     "kubectl_manifest." + d.Get("name").(string),
+		takeover: d.Get("takeover").(bool),
+	}, nil
+}
+
+// Derives the owner id of a kubectl_manifest from its name, formatted as a
+// UUID. The id does not depend on the state, so that the objects stamped by
+// a create which failed before reaching the state are not foreign to the
+// next attempt.
+func manifestOwnerID(name string) string {
+	sum := sha256.Sum256([]byte("kubectl_manifest." + name))
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8],
+		sum[8:10], sum[10:16])
+}
+
+// Returns the owner recorded in the state, without generating a new one
+func stateManifestOwner(d manifestArguments) *manifestOwner {
+	return &manifestOwner{
+		id:       d.Get("owner_id").(string),
+		name:     "kubectl_manifest." + d.Get("name").(string),
+		takeover: d.Get("takeover").(bool),
+	}
+}
+
+// Stamps the owner annotations and the manifest label on every document
+func (o *manifestOwner) stamp(manifestResources []string) ([]string,
+	error) {
+
+	stamped := make([]string, 0, len(manifestResources))
+	for _, manifestResource := range manifestResources {
+		obj, err := resource.DecodeObject(manifestResource)
+		if err != nil {
+			return nil, err
+		}
+		obj.SetAnnotation(ownerIDAnnotation, o.id)
+		obj.SetAnnotation(ownerNameAnnotation, o.name)
+		obj.SetLabel(manifestLabel, o.id)
+		encoded, err := obj.Encode()
+		if err != nil {
+			return nil, err
+		}
+		stamped = append(stamped, encoded)
+	}
+	return stamped, nil
+}
+
+// Fails when the live object is owned by another terraform resource, unless
+// the takeover flag is set. Objects without owner annotations (i.e. created
+// outside terraform) are adopted.
+func (o *manifestOwner) check(live resource.Object) error {
+	annotations := live.Annotations()
+	ownerID, ok := annotations[ownerIDAnnotation]
+	if !ok || ownerID == o.id || o.takeover {
+		return nil
+	}
+	return fmt.Errorf("%s %q is owned by %s (owner id %s), set takeover = "+
+		"true on %s to take it over", live.Kind(), live.Name(),
+		annotations[ownerNameAnnotation], ownerID, o.name)
+}
+
+// Checks the ownership of the object referenced by a resource handle, if it
+// still exists in the cluster
+func (o *manifestOwner) checkHandle(resourceHandle, namespace string,
+	kubectlCLIConfig *KubectlConfig) error {
+
+	stdout := &bytes.Buffer{}
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	getCommand := commandFactory.CreateGetJSONByHandleCommand(
+		resourceHandle, namespace, stdout)
+
+	if err := getCommand.RunCommand(); err != nil {
+		return err
+	}
+	return o.checkOutput(stdout.String())
+}
+
+func (o *manifestOwner) checkOutput(output string) error {
+	liveObjects, err := resource.DecodeObjects(output)
+	if err != nil {
+		return fmt.Errorf("decoding response: %v", err)
+	}
+	for _, live := range liveObjects {
+		if err := o.check(live); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package kubectl
+
+import (
+	"github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Ownership", func() {
+
+	const deployment = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  labels:
+    app: web
+`
+
+	owner := &manifestOwner{id: manifestOwnerID("web"),
+		name: "kubectl_manifest.web"}
+
+	It("Should derive the owner id from the name", func() {
+		Expect(manifestOwnerID("web")).To(Equal(owner.id))
+		Expect(manifestOwnerID("worker")).NotTo(Equal(owner.id))
+		Expect(owner.id).To(MatchRegexp(
+			"^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$"))
+	})
+
+	It("Should stamp the owner on every document", func() {
+		stamped, err := owner.stamp([]string{deployment})
+		Expect(err).To(BeNil())
+		Expect(stamped).To(HaveLen(1))
+
+		obj, err := resource.DecodeObject(stamped[0])
+		Expect(err).To(BeNil())
+		Expect(obj.Annotations()).To(Equal(map[string]string{
+			ownerIDAnnotation:   owner.id,
+			ownerNameAnnotation: "kubectl_manifest.web",
+		}))
+		Expect(obj.Labels()).To(Equal(map[string]string{
+			"app":         "web",
+			manifestLabel: owner.id,
+		}))
+	})
+
+	It("Should accept its own objects and adopt unowned ones", func() {
+		stamped, _ := owner.stamp([]string{deployment})
+		own, _ := resource.DecodeObject(stamped[0])
+		Expect(owner.check(own)).To(Succeed())
+
+		unowned, _ := resource.DecodeObject(deployment)
+		Expect(owner.check(unowned)).To(Succeed())
+	})
+
+	It("Should reject the objects of other owners", func() {
+		other := &manifestOwner{id: manifestOwnerID("api"),
+			name: "kubectl_manifest.api"}
+		stamped, _ := other.stamp([]string{deployment})
+		foreign, _ := resource.DecodeObject(stamped[0])
+
+		err := owner.check(foreign)
+		Expect(err).NotTo(BeNil())
+		Expect(err.Error()).To(ContainSubstring("kubectl_manifest.api"))
+
+		takeover := &manifestOwner{id: owner.id, name: owner.name,
+			takeover: true}
+		Expect(takeover.check(foreign)).To(Succeed())
+	})
+
+	It("Should check the objects listed by kubectl", func() {
+		other := &manifestOwner{id: manifestOwnerID("api"),
+			name: "kubectl_manifest.api"}
+		stamped, _ := other.stamp([]string{deployment})
+		foreign, _ := resource.DecodeObject(stamped[0])
+		output, _ := foreign.Encode()
+
+		Expect(owner.checkOutput("")).To(Succeed())
+		Expect(owner.checkOutput(output)).NotTo(Succeed())
+	})
+})
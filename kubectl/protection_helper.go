@@ -0,0 +1,118 @@
+package kubectl
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+)
+
+// number of the custom resources listed by the error refusing to delete
+// their CRD
+const listedCustomResources = 10
+
+const (
+	// the objects of a destroyed kubectl_manifest are deleted
+	OnDestroyDelete = "delete"
+	// the objects of a destroyed kubectl_manifest are left in the cluster
+	OnDestroyOrphan = "orphan"
+)
+
+func validateOnDestroy(v interface{}, k string) ([]string, []error) {
+	switch v.(string) {
+	case OnDestroyDelete, OnDestroyOrphan:
+		return nil, nil
+	}
+	return nil, []error{fmt.Errorf("%s must be one of %s or %s, got %q",
+		k, OnDestroyDelete, OnDestroyOrphan, v.(string))}
+}
+
+// Objects the provider never deletes, whichever resource they belong to:
+// objects of the protected kinds, objects of the protected namespaces and
+// the protected namespaces themselves
+type protectedObjects struct {
+	kinds      map[string]bool
+	namespaces map[string]bool
+}
+
+func newProtectedObjects(kinds, namespaces []string) *protectedObjects {
+	if len(kinds) == 0 && len(namespaces) == 0 {
+		return nil
+	}
+	protected := &protectedObjects{kinds: make(map[string]bool),
+		namespaces: make(map[string]bool)}
+	for _, kind := range kinds {
+		protected.kinds[strings.ToLower(kind)] = true
+	}
+	for _, namespace := range namespaces {
+		protected.namespaces[namespace] = true
+	}
+	return protected
+}
+
+// Checks whether the object, in the given namespace unless it sets one, is
+// protected
+func (p *protectedObjects) protects(obj resource.Object, namespace string) bool {
+	if p == nil || obj == nil {
+		return false
+	}
+	if obj.Namespace() != "" {
+		namespace = obj.Namespace()
+	}
+	if p.kinds[strings.ToLower(obj.Kind())] || p.namespaces[namespace] {
+		return true
+	}
+	return obj.Kind() == "Namespace" && p.namespaces[obj.Name()]
+}
+
+// Refuses to delete a CustomResourceDefinition while custom resources which
+// do not belong to the manifest exist, as they would be deleted along with
+// it. The name of a CRD is the resource type of its custom resources.
+func checkCRDInstances(crdName string, owner *manifestOwner,
+	kubectlCLIConfig *KubectlConfig) error {
+
+	stdout := &bytes.Buffer{}
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	getCommand := commandFactory.CreateGetAllCommand(crdName, stdout)
+	if err := getCommand.RunCommand(); err != nil {
+		if strings.Contains(err.Error(), "doesn't have a resource type") {
+			// the CRD is not served, no custom resource can exist
+			return nil
+		}
+		return fmt.Errorf("error while listing the custom resources of %s: %s",
+			crdName, err)
+	}
+	instances, err := resource.DecodeObjects(stdout.String())
+	if err != nil {
+		return fmt.Errorf("decoding response: %v", err)
+	}
+
+	foreign := make([]string, 0)
+	for _, instance := range instances {
+		if owner.id != "" &&
+			instance.Labels()[manifestLabel] == owner.id {
+			continue
+		}
+		name := instance.Name()
+		if instance.Namespace() != "" {
+			name = instance.Namespace() + "/" + name
+		}
+		foreign = append(foreign, name)
+	}
+	if len(foreign) == 0 {
+		return nil
+	}
+
+	listed := foreign
+	more := ""
+	if len(listed) > listedCustomResources {
+		listed = listed[:listedCustomResources]
+		more = fmt.Sprintf(" (and %d more)",
+			len(foreign)-listedCustomResources)
+	}
+	return fmt.Errorf("refusing to delete CustomResourceDefinition %s: "+
+		"%d custom resources not managed by %s would be deleted with it: "+
+		"%s%s. Set allow_crd_data_loss to delete it anyway", crdName,
+		len(foreign), owner.name, strings.Join(listed, ", "), more)
+}
@@ -1,6 +1,9 @@
 package kubectl
 
 import (
+	"encoding/base64"
+	"io/ioutil"
+
 	"github.com/hashicorp/terraform/helper/schema"
 )
 
@@ -8,10 +11,27 @@ type Config struct {
 	Kubeconfig  string
 	Kubecontent string
 	Kubecontext string
+	// field paths excluded from drift detection, indexed by resource kind
+	IgnoreFields map[string][]string
+	// labels and annotations added to every applied object
+	DefaultLabels      map[string]string
+	DefaultAnnotations map[string]string
+	// masks credentials and Secret values in errors and log lines
+	Redactor *Redactor
+	// records the operations of kubectl_manifest resources, if set
+	AuditLog *auditLog
+	// exports the spans of the provider operations, if set
+	Tracer *Tracer
+	// objects never deleted by the provider, if any
+	Protected *protectedObjects
+	// dry-run mode of the applies and deletes, empty when disabled
+	DryRun string
+	// span of the current operation, set by traceResource
+	span *Span
 }
 
 func Provider() *schema.Provider {
-	return &schema.Provider{
+	provider := &schema.Provider{
 		Schema: map[string]*schema.Schema{
 			"kubeconfig": &schema.Schema{
 				Type:     schema.TypeString,
@@ -26,17 +46,182 @@ func Provider() *schema.Provider {
 				Type:     schema.TypeString,
 				Optional: true,
 			},
+			"default_ignore_fields": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						"kind": &schema.Schema{
+							Type:     schema.TypeString,
+							Required: true,
+						},
+						"fields": &schema.Schema{
+							Type:     schema.TypeList,
+							Required: true,
+							Elem:     &schema.Schema{Type: schema.TypeString},
+						},
+					},
+				},
+			},
+			// kinds of the objects never deleted by the provider
+			"protected_kinds": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			// namespaces never deleted by the provider, along with their
+			// objects
+			"protected_namespaces": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			"default_labels": &schema.Schema{
+				Type:     schema.TypeMap,
+				Optional: true,
+			},
+			"default_annotations": &schema.Schema{
+				Type:     schema.TypeMap,
+				Optional: true,
+			},
+			"audit_log_path": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+			},
+			"tracing": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				MaxItems: 1,
+				Elem: &schema.Resource{
+					Schema: map[string]*schema.Schema{
+						// OTLP/HTTP traces endpoint of a collector
+						"endpoint": &schema.Schema{
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						// file the traces are appended to as OTLP/JSON lines
+						"file": &schema.Schema{
+							Type:     schema.TypeString,
+							Optional: true,
+						},
+						"headers": &schema.Schema{
+							Type:     schema.TypeMap,
+							Optional: true,
+						},
+					},
+				},
+			},
+			"redaction": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      RedactionDefault,
+				ValidateFunc: validateRedaction,
+			},
+			"dry_run": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      DryRunNone,
+				ValidateFunc: validateDryRun,
+			},
 		},
 		ResourcesMap: map[string]*schema.Resource{
-			"kubectl_manifest": resourceManifest(),
+			"kubectl_manifest":              resourceManifest(),
+			"kubectl_patch":                 resourcePatch(),
+			"kubectl_config_map_from_files": resourceConfigMapFromFiles(),
+			"kubectl_secret_from_files":     resourceSecretFromFiles(),
+			"kubectl_wait":                  resourceWait(),
+			"kubectl_rollout_restart":       resourceRolloutRestart(),
+			"kubectl_job_run":               resourceJobRun(),
+		},
+		DataSourcesMap: map[string]*schema.Resource{
+			"kubectl_object":              dataSourceObject(),
+			"kubectl_server_version":      dataSourceServerVersion(),
+			"kubectl_api_resources":       dataSourceAPIResources(),
+			"kubectl_kustomize_documents": dataSourceKustomizeDocuments(),
+			"kubectl_helm_template":       dataSourceHelmTemplate(),
 		},
 		ConfigureFunc: func(d *schema.ResourceData) (interface{}, error) {
+			redactor := newConfigRedactor(d)
 			config := &Config{
 				Kubeconfig:  d.Get("kubeconfig").(string),
 				Kubecontent: d.Get("kubecontent").(string),
 				Kubecontext: d.Get("kubecontext").(string),
+				IgnoreFields: expandIgnoreFields(
+					d.Get("default_ignore_fields").([]interface{})),
+				DefaultLabels: expandStringMap(
+					d.Get("default_labels").(map[string]interface{})),
+				DefaultAnnotations: expandStringMap(
+					d.Get("default_annotations").(map[string]interface{})),
+				Protected: newProtectedObjects(
+					expandStringList(d.Get("protected_kinds").([]interface{})),
+					expandStringList(
+						d.Get("protected_namespaces").([]interface{}))),
+				Redactor: redactor,
+				AuditLog: newAuditLog(d.Get("audit_log_path").(string),
+					redactor),
+				Tracer: expandTracer(d.Get("tracing").([]interface{}),
+					redactor),
+			}
+			if dryRun := d.Get("dry_run").(string); dryRun != DryRunNone {
+				config.DryRun = dryRun
 			}
 			return config, nil
 		},
 	}
+
+	for name, r := range provider.ResourcesMap {
+		redactResourceErrors(traceResource(name, r))
+	}
+	for name, r := range provider.DataSourcesMap {
+		redactResourceErrors(traceResource("data."+name, r))
+	}
+	return provider
+}
+
+// Builds the redactor of the provider, registering the credentials of the
+// kubeconfig and masking the provider log lines
+func newConfigRedactor(d *schema.ResourceData) *Redactor {
+	redactor := NewRedactor(d.Get("redaction").(string))
+	if !redactor.enabled() {
+		return redactor
+	}
+
+	if kubecontent := d.Get("kubecontent").(string); kubecontent != "" {
+		redactor.AddValues(kubecontent)
+		if decoded, err := base64.StdEncoding.DecodeString(kubecontent); err == nil {
+			redactor.AddKubeconfig(string(decoded))
+		}
+	}
+	if kubeconfig := d.Get("kubeconfig").(string); kubeconfig != "" {
+		if content, err := ioutil.ReadFile(kubeconfig); err == nil {
+			redactor.AddKubeconfig(string(content))
+		}
+	}
+
+	providerLogRedaction.install(redactor)
+	return redactor
+}
+
+func expandIgnoreFields(blocks []interface{}) map[string][]string {
+	ignoreFields := make(map[string][]string)
+
+	for _, block := range blocks {
+		blockMap, ok := block.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		kind := blockMap["kind"].(string)
+		for _, field := range blockMap["fields"].([]interface{}) {
+			ignoreFields[kind] = append(ignoreFields[kind], field.(string))
+		}
+	}
+	return ignoreFields
+}
+
+func expandStringMap(m map[string]interface{}) map[string]string {
+	strs := make(map[string]string, len(m))
+	for k, v := range m {
+		strs[k] = v.(string)
+	}
+	return strs
 }
@@ -0,0 +1,121 @@
+package kubectl
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// Lists the resource types that get pruned: the ones of the documents in the
+// previous and current state, restricted to the ones in the configured
+// allowlist when it is set.
+func pruneGroupVersionKinds(allowlist []string, tfResourceSets ...*schema.Set) (
+	[]resource.GroupVersionKind, error) {
+
+	allowed := make(map[resource.GroupVersionKind]bool)
+	for _, entry := range allowlist {
+		gvk, err := resource.ParseGroupVersionKind(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prune_allowlist entry: %s", err)
+		}
+		allowed[gvk] = true
+	}
+
+	gvks := make([]resource.GroupVersionKind, 0)
+	seen := make(map[resource.GroupVersionKind]bool)
+	for _, tfResources := range tfResourceSets {
+		for _, tfResource := range tfResources.List() {
+			resourceObj, ok := tfResource.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			content, err := base64.StdEncoding.DecodeString(
+				resourceObj["content"].(string))
+			if err != nil {
+				continue
+			}
+			obj, err := resource.DecodeObject(string(content))
+			if err != nil {
+				continue
+			}
+			gvk := obj.GroupVersionKind()
+			if seen[gvk] || (len(allowed) != 0 && !allowed[gvk]) {
+				continue
+			}
+			seen[gvk] = true
+			gvks = append(gvks, gvk)
+		}
+	}
+	return gvks, nil
+}
+
+// Deletes the objects labelled as belonging to the manifest, which are not
+// part of the applied resources anymore.
+//
+// This catches the objects left behind by partially failed applies, which
+// never reached the terraform state. Pruned CRDs are checked for custom
+// resources of other owners, unless allowCRDDataLoss is set.
+func pruneResources(owner *manifestOwner, tfResources *schema.Set,
+	gvks []resource.GroupVersionKind, audit *manifestAudit,
+	protected *protectedObjects, allowCRDDataLoss bool,
+	kubectlCLIConfig *KubectlConfig) error {
+
+	kept := make(map[string]bool)
+	for _, tfResource := range tfResources.List() {
+		resourceObj := tfResource.(map[string]interface{})
+		kept[resourceObj["uid"].(string)] = true
+	}
+
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	label := manifestLabel + "=" + owner.id
+
+	for _, gvk := range gvks {
+		stdout := &bytes.Buffer{}
+		getCommand := commandFactory.CreateGetAllByLabelCommand(
+			gvk.ResourceArg(), label, stdout)
+
+		if err := getCommand.RunCommand(); err != nil {
+			log.Printf("[WARN] skipping prune of %s: %s", gvk, err)
+			continue
+		}
+		liveObjects, err := resource.DecodeObjects(stdout.String())
+		if err != nil {
+			return fmt.Errorf("decoding response: %v", err)
+		}
+
+		for _, live := range liveObjects {
+			if kept[live.UID()] {
+				continue
+			}
+			if protected.protects(live, "") {
+				log.Printf("[WARN] not pruning protected object %s %s/%s",
+					gvk, live.Namespace(), live.Name())
+				continue
+			}
+			if live.Kind() == "CustomResourceDefinition" && !allowCRDDataLoss {
+				err := checkCRDInstances(live.Name(), owner, kubectlCLIConfig)
+				if err != nil {
+					return err
+				}
+			}
+			log.Printf("[INFO] pruning %s %s/%s", gvk, live.Namespace(),
+				live.Name())
+
+			resourceHandle := gvk.ResourceArg() + "/" + live.Name()
+			deleteCommand := commandFactory.CreateDeleteByHandleCommand(
+				resourceHandle, live.Namespace())
+			start := time.Now()
+			err := deleteCommand.RunCommand()
+			audit.record("prune", live, live.Namespace(), "", start, err)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
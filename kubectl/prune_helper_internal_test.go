@@ -0,0 +1,46 @@
+package kubectl
+
+import (
+	"encoding/base64"
+
+	"github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pruned types", func() {
+
+	tfResource := func(uid, content string) map[string]interface{} {
+		return map[string]interface{}{"selflink": uid, "uid": uid,
+			"content": base64.StdEncoding.EncodeToString([]byte(content))}
+	}
+	tfOldResources := schema.NewSet(HashResource, []interface{}{
+		tfResource("1a2b", "apiVersion: v1\nkind: ConfigMap\n"),
+		tfResource("3c4d", "apiVersion: apps/v1\nkind: Deployment\n")})
+	tfResources := schema.NewSet(HashResource, []interface{}{
+		tfResource("5e6f", "apiVersion: apps/v1\nkind: Deployment\n")})
+
+	configMap := resource.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	deployment := resource.GroupVersionKind{Group: "apps", Version: "v1",
+		Kind: "Deployment"}
+
+	It("Should list the types of the previous and current documents", func() {
+		gvks, err := pruneGroupVersionKinds(nil, tfOldResources, tfResources)
+		Expect(err).To(BeNil())
+		Expect(gvks).To(ConsistOf(configMap, deployment))
+	})
+
+	It("Should restrict the types to the allowlist", func() {
+		gvks, err := pruneGroupVersionKinds([]string{"core/v1/ConfigMap",
+			"batch/v1/Job"}, tfOldResources, tfResources)
+		Expect(err).To(BeNil())
+		Expect(gvks).To(ConsistOf(configMap))
+	})
+
+	It("Should reject invalid allowlist entries", func() {
+		_, err := pruneGroupVersionKinds([]string{"ConfigMap"}, tfResources)
+		Expect(err).NotTo(BeNil())
+	})
+})
@@ -0,0 +1,315 @@
+package kubectl
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+	"github.com/ghodss/yaml"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+const (
+	// errors and log lines are left untouched
+	RedactionNone = "none"
+	// credentials and Secret values are masked
+	RedactionDefault = "default"
+	// command lines are also reduced to the kubectl subcommand
+	RedactionStrict = "strict"
+
+	redactedValue = "<redacted>"
+	// shorter values are not masked, as they would mask unrelated text
+	minRedactedLength = 4
+)
+
+var redactionPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----.*?-----END [A-Z ]*PRIVATE KEY-----`),
+		redactedValue},
+	{regexp.MustCompile(`(?i)(bearer\s+)[^\s"']+`), "${1}" + redactedValue},
+	{regexp.MustCompile(`(--token[= ])[^\s]+`), "${1}" + redactedValue},
+	{regexp.MustCompile(`((?:token|client-key-data|password)["']?\s*[:=]\s*["']?)[^\s"',}]+`),
+		"${1}" + redactedValue},
+}
+
+// Redactor masks credentials and Secret values in errors and log lines. The
+// values known to be sensitive (Secret values applied by the provider,
+// credentials of the kubeconfig) are masked wherever they appear, along with
+// the usual shapes of tokens and private keys.
+type Redactor struct {
+	Level string
+
+	mu     sync.RWMutex
+	values map[string]bool
+}
+
+func NewRedactor(level string) *Redactor {
+	return &Redactor{Level: level, values: make(map[string]bool)}
+}
+
+func validateRedaction(v interface{}, k string) ([]string, []error) {
+	switch v.(string) {
+	case RedactionNone, RedactionDefault, RedactionStrict:
+		return nil, nil
+	}
+	return nil, []error{fmt.Errorf("%s must be one of %s, %s or %s, got %q",
+		k, RedactionNone, RedactionDefault, RedactionStrict, v.(string))}
+}
+
+func (r *Redactor) enabled() bool {
+	return r != nil && r.Level != RedactionNone
+}
+
+// AddValues registers sensitive values, masked wherever they appear
+func (r *Redactor) AddValues(values ...string) {
+	if !r.enabled() {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, value := range values {
+		if len(value) >= minRedactedLength {
+			r.values[value] = true
+		}
+	}
+}
+
+// AddManifest registers the values of the Secrets of a manifest, both in
+// their plain and base64 encoded forms
+func (r *Redactor) AddManifest(manifest string) {
+	if !r.enabled() {
+		return
+	}
+	documents, err := resource.SplitYAMLDocument(manifest)
+	if err != nil {
+		return
+	}
+	for _, document := range documents {
+		obj, err := resource.DecodeObject(document)
+		if err != nil || obj.Kind() != "Secret" {
+			continue
+		}
+		data, err := obj.Data()
+		if err != nil {
+			continue
+		}
+		stringData, _ := obj["stringData"].(map[string]interface{})
+		for key, value := range stringData {
+			data[key] = []byte(fmt.Sprint(value))
+		}
+		for _, value := range data {
+			r.AddValues(string(value),
+				base64.StdEncoding.EncodeToString(value))
+		}
+	}
+}
+
+// AddPatch registers every string value of a patch
+func (r *Redactor) AddPatch(patch string) {
+	if !r.enabled() {
+		return
+	}
+	var decoded interface{}
+	if err := yaml.Unmarshal([]byte(patch), &decoded); err != nil {
+		return
+	}
+	r.addLeaves(decoded)
+}
+
+// AddKubeconfig registers the credentials of a kubeconfig, along with the
+// kubeconfig content itself
+func (r *Redactor) AddKubeconfig(kubeconfig string) {
+	if !r.enabled() || kubeconfig == "" {
+		return
+	}
+	r.AddValues(kubeconfig)
+
+	var config struct {
+		Users []struct {
+			User map[string]interface{} `json:"user"`
+		} `json:"users"`
+	}
+	if err := yaml.Unmarshal([]byte(kubeconfig), &config); err != nil {
+		return
+	}
+	for _, user := range config.Users {
+		for _, key := range []string{"token", "client-key-data",
+			"client-certificate-data", "password"} {
+			if value, ok := user.User[key].(string); ok {
+				r.AddValues(value)
+			}
+		}
+	}
+}
+
+func (r *Redactor) addLeaves(value interface{}) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for _, v := range typed {
+			r.addLeaves(v)
+		}
+	case []interface{}:
+		for _, v := range typed {
+			r.addLeaves(v)
+		}
+	case string:
+		r.AddValues(typed)
+	}
+}
+
+// Redact masks the sensitive values of a string
+func (r *Redactor) Redact(s string) string {
+	if !r.enabled() {
+		return s
+	}
+
+	r.mu.RLock()
+	values := make([]string, 0, len(r.values))
+	for value := range r.values {
+		values = append(values, value)
+	}
+	r.mu.RUnlock()
+	// longer values first, as they may contain shorter ones
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+	for _, value := range values {
+		s = strings.Replace(s, value, redactedValue, -1)
+	}
+
+	for _, redaction := range redactionPatterns {
+		s = redaction.pattern.ReplaceAllString(s, redaction.replacement)
+	}
+	return s
+}
+
+// RedactError masks the sensitive values of an error message
+func (r *Redactor) RedactError(err error) error {
+	if err == nil || !r.enabled() {
+		return err
+	}
+	return errors.New(r.Redact(err.Error()))
+}
+
+// Redacts a command line, reducing it to its subcommand in strict mode
+func (r *Redactor) commandLine(args []string) string {
+	if r == nil || r.Level != RedactionStrict {
+		return r.Redact(strings.Join(args, " "))
+	}
+	return commandName(args)
+}
+
+// Reduces a kubectl command line to its subcommand, skipping the global
+// flags rendered by KubectlConfig.RenderArgs
+func commandName(args []string) string {
+	for i := 1; i < len(args); i++ {
+		switch args[i] {
+		case "--kubeconfig", "--context":
+			i++
+		default:
+			return args[0] + " " + args[i]
+		}
+	}
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+// Log writer masking the sensitive values of log lines, with the redactors
+// of every configured provider
+type redactingWriter struct {
+	mu        sync.RWMutex
+	redactors []*Redactor
+	out       io.Writer
+}
+
+func (w *redactingWriter) add(redactor *Redactor) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.redactors = append(w.redactors, redactor)
+}
+
+func (w *redactingWriter) Write(p []byte) (int, error) {
+	w.mu.RLock()
+	line := string(p)
+	for _, redactor := range w.redactors {
+		line = redactor.Redact(line)
+	}
+	w.mu.RUnlock()
+
+	if _, err := w.out.Write([]byte(line)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Wraps the log output in a single redacting writer, however many times the
+// providers are configured
+type logRedaction struct {
+	once   sync.Once
+	writer *redactingWriter
+}
+
+var providerLogRedaction = &logRedaction{}
+
+func (l *logRedaction) install(redactor *Redactor) {
+	l.once.Do(func() {
+		l.writer = &redactingWriter{out: log.Writer()}
+		log.SetOutput(l.writer)
+	})
+	l.writer.add(redactor)
+}
+
+// Masks the sensitive values of the errors returned by a resource or data
+// source
+func redactResourceErrors(r *schema.Resource) *schema.Resource {
+	redactor := func(m interface{}) *Redactor {
+		if config, ok := m.(*Config); ok {
+			return config.Redactor
+		}
+		return nil
+	}
+
+	if create := r.Create; create != nil {
+		r.Create = func(d *schema.ResourceData, m interface{}) error {
+			return redactor(m).RedactError(create(d, m))
+		}
+	}
+	if read := r.Read; read != nil {
+		r.Read = func(d *schema.ResourceData, m interface{}) error {
+			return redactor(m).RedactError(read(d, m))
+		}
+	}
+	if update := r.Update; update != nil {
+		r.Update = func(d *schema.ResourceData, m interface{}) error {
+			return redactor(m).RedactError(update(d, m))
+		}
+	}
+	if del := r.Delete; del != nil {
+		r.Delete = func(d *schema.ResourceData, m interface{}) error {
+			return redactor(m).RedactError(del(d, m))
+		}
+	}
+	if exists := r.Exists; exists != nil {
+		r.Exists = func(d *schema.ResourceData, m interface{}) (bool, error) {
+			ok, err := exists(d, m)
+			return ok, redactor(m).RedactError(err)
+		}
+	}
+	if customizeDiff := r.CustomizeDiff; customizeDiff != nil {
+		r.CustomizeDiff = func(d *schema.ResourceDiff, m interface{}) error {
+			return redactor(m).RedactError(customizeDiff(d, m))
+		}
+	}
+	return r
+}
@@ -0,0 +1,44 @@
+package kubectl
+
+import (
+	"bytes"
+	"log"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Log redaction", func() {
+
+	var output *bytes.Buffer
+	previous := log.Writer()
+
+	BeforeEach(func() {
+		output = &bytes.Buffer{}
+		log.SetOutput(output)
+	})
+
+	AfterEach(func() {
+		log.SetOutput(previous)
+	})
+
+	It("Should wrap the log output once for every configured provider", func() {
+		redaction := &logRedaction{}
+		first := NewRedactor(RedactionDefault)
+		first.AddValues("hunter2")
+		second := NewRedactor(RedactionDefault)
+		second.AddValues("correcthorse")
+
+		redaction.install(first)
+		writer := log.Writer()
+		redaction.install(second)
+		Expect(log.Writer()).To(BeIdenticalTo(writer))
+
+		log.Printf("passwords: hunter2, correcthorse")
+		Expect(strings.Count(output.String(), "\n")).To(Equal(1))
+		Expect(output.String()).To(ContainSubstring("passwords: "))
+		Expect(output.String()).NotTo(ContainSubstring("hunter2"))
+		Expect(output.String()).NotTo(ContainSubstring("correcthorse"))
+	})
+})
@@ -14,3 +14,12 @@ type KubectlItem struct {
 		UID      string `json:"uid"`
 	} `json:"metadata"`
 }
+
+type KubectlVersionResponse struct {
+	ServerVersion struct {
+		Major      string `json:"major"`
+		Minor      string `json:"minor"`
+		GitVersion string `json:"gitVersion"`
+		Platform   string `json:"platform"`
+	} `json:"serverVersion"`
+}
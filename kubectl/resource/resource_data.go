@@ -0,0 +1,190 @@
+package resource
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// NewDataObject builds a ConfigMap or Secret holding the given data. Binary
+// values are stored in the `binaryData` of ConfigMaps.
+func NewDataObject(kind, name, namespace string,
+	data map[string][]byte) Object {
+
+	metadata := map[string]interface{}{"name": name}
+	if namespace != "" {
+		metadata["namespace"] = namespace
+	}
+	obj := Object{
+		"apiVersion": "v1",
+		"kind":       kind,
+		"metadata":   metadata,
+	}
+
+	stringData := make(map[string]interface{})
+	binaryData := make(map[string]interface{})
+	for key, value := range data {
+		if kind != "Secret" && utf8.Valid(value) {
+			stringData[key] = string(value)
+		} else {
+			binaryData[key] = base64.StdEncoding.EncodeToString(value)
+		}
+	}
+
+	if kind == "Secret" {
+		obj["data"] = binaryData
+		return obj
+	}
+	obj["data"] = stringData
+	if len(binaryData) != 0 {
+		obj["binaryData"] = binaryData
+	}
+	return obj
+}
+
+// Data returns the decoded data of a ConfigMap or Secret.
+func (o Object) Data() (map[string][]byte, error) {
+	data := make(map[string][]byte)
+	binaryFields := []string{"binaryData"}
+
+	if o.Kind() == "Secret" {
+		binaryFields = []string{"data"}
+	} else {
+		values, _ := o["data"].(map[string]interface{})
+		for key, value := range values {
+			data[key] = []byte(fmt.Sprint(value))
+		}
+	}
+
+	for _, field := range binaryFields {
+		values, _ := o[field].(map[string]interface{})
+		for key, value := range values {
+			decoded, err := base64.StdEncoding.DecodeString(fmt.Sprint(value))
+			if err != nil {
+				return nil, fmt.Errorf("decoding %s.%s: %v", field, key, err)
+			}
+			data[key] = decoded
+		}
+	}
+	return data, nil
+}
+
+// HashData returns a stable hash of the keys and values of the data.
+func HashData(data map[string][]byte) string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	hash := sha256.New()
+	for _, key := range keys {
+		fmt.Fprintf(hash, "%s\x00%d\x00", key, len(data[key]))
+		hash.Write(data[key])
+	}
+	return fmt.Sprintf("%x", hash.Sum(nil))
+}
+
+const (
+	// secretHashPrefix marks the Secret values replaced by their keyed hash,
+	// which cannot be mistaken for base64 encoded values
+	secretHashPrefix = "hmac-sha256:"
+	// legacySecretHashPrefix marks the unkeyed hashes of earlier releases
+	legacySecretHashPrefix = "sha256:"
+
+	secretSaltLength = 16
+)
+
+// NewSecretSalt generates the random key of the hashes of a Secret.
+func NewSecretSalt() ([]byte, error) {
+	salt := make([]byte, secretSaltLength)
+	_, err := rand.Read(salt)
+	return salt, err
+}
+
+// HashSecretData replaces the values of a Secret with their HMAC keyed by
+// the salt, so that Secrets can be compared without storing their values,
+// nor hashes which could be brute-forced without the salt. The salt is
+// stored along with each hash. The `stringData` values are hashed into
+// `data`, where the api server stores them. Objects of other kinds are left
+// untouched.
+func (o Object) HashSecretData(salt []byte) error {
+	if o.Kind() != "Secret" {
+		return nil
+	}
+	data, _ := o["data"].(map[string]interface{})
+	hashed := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		encoded := fmt.Sprint(value)
+		if isSecretHash(encoded) {
+			hashed[key] = encoded
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("decoding data.%s: %v", key, err)
+		}
+		hashed[key] = hashSecretValue(salt, decoded)
+	}
+
+	stringData, _ := o["stringData"].(map[string]interface{})
+	for key, value := range stringData {
+		hashed[key] = hashSecretValue(salt, []byte(fmt.Sprint(value)))
+	}
+	delete(o, "stringData")
+
+	if len(hashed) != 0 {
+		o["data"] = hashed
+	}
+	return nil
+}
+
+// SecretDataHashed reports whether the values of a Secret were replaced by
+// their hash.
+func (o Object) SecretDataHashed() bool {
+	data, _ := o["data"].(map[string]interface{})
+	for _, value := range data {
+		if isSecretHash(fmt.Sprint(value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// SecretDataSalt returns the salt of the hashed values of a Secret, nil when
+// its values are not hashed.
+func (o Object) SecretDataSalt() []byte {
+	data, _ := o["data"].(map[string]interface{})
+	for _, value := range data {
+		hash := fmt.Sprint(value)
+		if !strings.HasPrefix(hash, secretHashPrefix) {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimPrefix(hash, secretHashPrefix),
+			":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		if salt, err := hex.DecodeString(parts[0]); err == nil {
+			return salt
+		}
+	}
+	return nil
+}
+
+func isSecretHash(value string) bool {
+	return strings.HasPrefix(value, secretHashPrefix) ||
+		strings.HasPrefix(value, legacySecretHashPrefix)
+}
+
+func hashSecretValue(salt, value []byte) string {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(value)
+	return fmt.Sprintf("%s%x:%x", secretHashPrefix, salt, mac.Sum(nil))
+}
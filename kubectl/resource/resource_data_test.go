@@ -0,0 +1,124 @@
+package resource_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+)
+
+var _ = Describe("ResourceData", func() {
+
+	data := map[string][]byte{
+		"dashboard.json": []byte(`{"title": "nodes"}`),
+		"logo.png":       {0x89, 0x50, 0x4e, 0x47, 0xff},
+	}
+
+	Describe("NewDataObject", func() {
+
+		It("Should store binary values of config maps apart", func() {
+			obj := NewDataObject("ConfigMap", "dashboards", "monitoring", data)
+
+			value, _ := obj.EvaluateJSONPath("{.data['dashboard.json']}")
+			Expect(value).To(Equal(`{"title": "nodes"}`))
+			value, _ = obj.EvaluateJSONPath("{.binaryData['logo.png']}")
+			Expect(value).To(Equal("iVBOR/8="))
+			Expect(obj.Namespace()).To(Equal("monitoring"))
+		})
+
+		It("Should encode every value of secrets", func() {
+			obj := NewDataObject("Secret", "dashboards", "", data)
+
+			value, _ := obj.EvaluateJSONPath("{.data['dashboard.json']}")
+			Expect(value).To(Equal("eyJ0aXRsZSI6ICJub2RlcyJ9"))
+		})
+
+		It("Should decode the data it stores", func() {
+			for _, kind := range []string{"ConfigMap", "Secret"} {
+				decoded, err := NewDataObject(kind, "dashboards", "", data).Data()
+				Expect(err).To(BeNil())
+				Expect(decoded).To(Equal(data))
+			}
+		})
+	})
+
+	Describe("HashData", func() {
+
+		It("Should only depend on the data", func() {
+			Expect(HashData(data)).To(Equal(HashData(map[string][]byte{
+				"logo.png":       {0x89, 0x50, 0x4e, 0x47, 0xff},
+				"dashboard.json": []byte(`{"title": "nodes"}`),
+			})))
+			Expect(HashData(data)).NotTo(Equal(HashData(map[string][]byte{
+				"dashboard.json": []byte(`{"title": "nodes"}`),
+			})))
+		})
+	})
+
+	Describe("HashSecretData", func() {
+
+		salt := []byte("0123456789abcdef")
+
+		It("Should hash data and string data alike", func() {
+			desired, err := DecodeObject(`{"kind": "Secret", "metadata": {"name": "db"},
+				"stringData": {"password": "hunter2"}}`)
+			Expect(err).To(BeNil())
+			live, err := DecodeObject(`{"kind": "Secret", "metadata": {"name": "db"},
+				"data": {"password": "aHVudGVyMg=="}, "type": "Opaque"}`)
+			Expect(err).To(BeNil())
+
+			Expect(desired.HashSecretData(salt)).To(BeNil())
+			Expect(live.HashSecretData(salt)).To(BeNil())
+			Expect(desired).NotTo(HaveKey("stringData"))
+			Expect(desired.SecretDataHashed()).To(BeTrue())
+			Expect(IsSubset(desired, live)).To(BeTrue())
+
+			value, _ := desired.EvaluateJSONPath("data.password")
+			Expect(value).NotTo(ContainSubstring("hunter2"))
+		})
+
+		It("Should not hash values twice", func() {
+			obj, err := DecodeObject(`{"kind": "Secret", "data": {"password": "aHVudGVyMg=="}}`)
+			Expect(err).To(BeNil())
+			Expect(obj.HashSecretData(salt)).To(BeNil())
+			hashed, _ := obj.EvaluateJSONPath("data.password")
+
+			Expect(obj.HashSecretData(salt)).To(BeNil())
+			value, _ := obj.EvaluateJSONPath("data.password")
+			Expect(value).To(Equal(hashed))
+		})
+
+		It("Should key the hashes with the salt stored along with them", func() {
+			secret := `{"kind": "Secret", "data": {"password": "aHVudGVyMg=="}}`
+			obj, _ := DecodeObject(secret)
+			Expect(obj.HashSecretData(salt)).To(BeNil())
+			hashed, _ := obj.EvaluateJSONPath("data.password")
+			Expect(obj.SecretDataSalt()).To(Equal(salt))
+			// unkeyed SHA-256 of hunter2
+			Expect(hashed).NotTo(ContainSubstring(
+				"f52fbd32b2b3b86ff88ef6c490628285f482af15ddcb29541f94bcf526a3f6c7"))
+
+			other, _ := DecodeObject(secret)
+			Expect(other.HashSecretData([]byte("fedcba9876543210"))).To(BeNil())
+			otherHashed, _ := other.EvaluateJSONPath("data.password")
+			Expect(otherHashed).NotTo(Equal(hashed))
+		})
+
+		It("Should keep the unkeyed hashes of earlier releases", func() {
+			obj, _ := DecodeObject(`{"kind": "Secret", "data": {"password":
+				"sha256:f52fbd32b2b3b86ff88ef6c490628285f482af15ddcb29541f94bcf526a3f6c7"}}`)
+			Expect(obj.SecretDataHashed()).To(BeTrue())
+			Expect(obj.SecretDataSalt()).To(BeNil())
+			Expect(obj.HashSecretData(salt)).To(BeNil())
+			value, _ := obj.EvaluateJSONPath("data.password")
+			Expect(value).To(HavePrefix("sha256:"))
+		})
+
+		It("Should leave other kinds untouched", func() {
+			obj := NewDataObject("ConfigMap", "settings", "",
+				map[string][]byte{"level": []byte("debug")})
+			Expect(obj.HashSecretData(salt)).To(BeNil())
+			Expect(obj.SecretDataHashed()).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,301 @@
+package resource
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A served replacement of a deprecated api version, along with the field
+// migrations required by the new version. The migration fails when the new
+// version requires fields that cannot be derived from the object.
+type apiReplacement struct {
+	apiVersion string
+	migrate    func(Object) error
+}
+
+// Replacements of the deprecated api versions removed by kubernetes 1.16,
+// 1.22 and 1.25, indexed by `<apiVersion>/<kind>` and ordered by preference
+var deprecatedAPIs = map[string][]apiReplacement{
+	"extensions/v1beta1/Deployment": {{"apps/v1", migrateWorkload}},
+	"extensions/v1beta1/DaemonSet":  {{"apps/v1", migrateWorkload}},
+	"extensions/v1beta1/ReplicaSet": {{"apps/v1", migrateWorkload}},
+	"apps/v1beta1/Deployment":       {{"apps/v1", migrateWorkload}},
+	"apps/v1beta1/StatefulSet":      {{"apps/v1", migrateWorkload}},
+	"apps/v1beta2/Deployment":       {{"apps/v1", migrateWorkload}},
+	"apps/v1beta2/StatefulSet":      {{"apps/v1", migrateWorkload}},
+	"apps/v1beta2/DaemonSet":        {{"apps/v1", migrateWorkload}},
+	"apps/v1beta2/ReplicaSet":       {{"apps/v1", migrateWorkload}},
+
+	"extensions/v1beta1/NetworkPolicy": {{"networking.k8s.io/v1", nil}},
+	"extensions/v1beta1/PodSecurityPolicy": {
+		{"policy/v1beta1", nil}},
+	"extensions/v1beta1/Ingress": {
+		{"networking.k8s.io/v1", migrateIngress},
+		{"networking.k8s.io/v1beta1", nil}},
+	"networking.k8s.io/v1beta1/Ingress": {
+		{"networking.k8s.io/v1", migrateIngress}},
+	"networking.k8s.io/v1beta1/IngressClass": {
+		{"networking.k8s.io/v1", nil}},
+
+	"rbac.authorization.k8s.io/v1beta1/Role": {
+		{"rbac.authorization.k8s.io/v1", nil}},
+	"rbac.authorization.k8s.io/v1beta1/ClusterRole": {
+		{"rbac.authorization.k8s.io/v1", nil}},
+	"rbac.authorization.k8s.io/v1beta1/RoleBinding": {
+		{"rbac.authorization.k8s.io/v1", nil}},
+	"rbac.authorization.k8s.io/v1beta1/ClusterRoleBinding": {
+		{"rbac.authorization.k8s.io/v1", nil}},
+
+	"batch/v1beta1/CronJob":              {{"batch/v1", nil}},
+	"policy/v1beta1/PodDisruptionBudget": {{"policy/v1", nil}},
+	"autoscaling/v2beta1/HorizontalPodAutoscaler": {
+		{"autoscaling/v2", migrateHorizontalPodAutoscaler},
+		{"autoscaling/v2beta2", migrateHorizontalPodAutoscaler}},
+	"autoscaling/v2beta2/HorizontalPodAutoscaler": {
+		{"autoscaling/v2", nil}},
+	"scheduling.k8s.io/v1beta1/PriorityClass": {
+		{"scheduling.k8s.io/v1", nil}},
+	"coordination.k8s.io/v1beta1/Lease":   {{"coordination.k8s.io/v1", nil}},
+	"storage.k8s.io/v1beta1/StorageClass": {{"storage.k8s.io/v1", nil}},
+	"storage.k8s.io/v1beta1/CSIDriver":    {{"storage.k8s.io/v1", nil}},
+	"storage.k8s.io/v1beta1/CSINode":      {{"storage.k8s.io/v1", nil}},
+
+	"admissionregistration.k8s.io/v1beta1/MutatingWebhookConfiguration": {
+		{"admissionregistration.k8s.io/v1", migrateWebhooks}},
+	"admissionregistration.k8s.io/v1beta1/ValidatingWebhookConfiguration": {
+		{"admissionregistration.k8s.io/v1", migrateWebhooks}},
+	"apiextensions.k8s.io/v1beta1/CustomResourceDefinition": {
+		{"apiextensions.k8s.io/v1", migrateCustomResourceDefinition}},
+}
+
+// ConvertDeprecatedAPI rewrites an object using a deprecated api version to
+// the first replacement served by the cluster, migrating the fields that
+// changed between versions. The new api version is returned, or an empty
+// string when the object was left untouched, i.e. when its api version is
+// not deprecated or when no replacement is served, or the object cannot be
+// migrated, but the deprecated version still is.
+func (o Object) ConvertDeprecatedAPI(served map[string]bool) (string, error) {
+	replacements, ok := deprecatedAPIs[o.APIVersion()+"/"+o.Kind()]
+	if !ok {
+		return "", nil
+	}
+	for _, replacement := range replacements {
+		if !served[replacement.apiVersion] {
+			continue
+		}
+		converted := o.Copy()
+		converted["apiVersion"] = replacement.apiVersion
+		if replacement.migrate != nil {
+			if err := replacement.migrate(converted); err != nil {
+				if served[o.APIVersion()] {
+					return "", nil
+				}
+				return "", fmt.Errorf("%s %q uses the removed api version %s, "+
+					"and cannot be converted to %s: %v", o.Kind(), o.Name(),
+					o.APIVersion(), replacement.apiVersion, err)
+			}
+		}
+		for key := range o {
+			delete(o, key)
+		}
+		for key, value := range converted {
+			o[key] = value
+		}
+		return replacement.apiVersion, nil
+	}
+	if served[o.APIVersion()] {
+		return "", nil
+	}
+
+	candidates := make([]string, 0, len(replacements))
+	for _, replacement := range replacements {
+		candidates = append(candidates, replacement.apiVersion)
+	}
+	return "", fmt.Errorf("%s %q uses the deprecated api version %s, which "+
+		"is not served by the cluster, and none of its replacements (%s) is "+
+		"served either", o.Kind(), o.Name(), o.APIVersion(),
+		strings.Join(candidates, ", "))
+}
+
+// The apps/v1 workloads require an explicit selector, which used to default
+// to the labels of the pod template
+func migrateWorkload(o Object) error {
+	spec, ok := o["spec"].(map[string]interface{})
+	if !ok || spec["selector"] != nil {
+		return nil
+	}
+	labels := o.Lookup([]string{"spec", "template", "metadata", "labels"})
+	if len(labels) == 0 {
+		return nil
+	}
+	spec["selector"] = map[string]interface{}{
+		"matchLabels": deepCopy(labels[0]),
+	}
+	return nil
+}
+
+// The networking.k8s.io/v1 ingresses renamed `backend` to `defaultBackend`,
+// moved the service of backends under `service` and require a `pathType`
+func migrateIngress(o Object) error {
+	spec, ok := o["spec"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if backend, ok := spec["backend"].(map[string]interface{}); ok {
+		delete(spec, "backend")
+		spec["defaultBackend"] = migrateIngressBackend(backend)
+	}
+
+	rules, _ := spec["rules"].([]interface{})
+	for _, rule := range rules {
+		paths := lookup(rule, []string{"http", "paths"})
+		if len(paths) == 0 {
+			continue
+		}
+		pathList, _ := paths[0].([]interface{})
+		for _, path := range pathList {
+			path, ok := path.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if _, ok := path["pathType"]; !ok {
+				path["pathType"] = "ImplementationSpecific"
+			}
+			if backend, ok := path["backend"].(map[string]interface{}); ok {
+				path["backend"] = migrateIngressBackend(backend)
+			}
+		}
+	}
+	return nil
+}
+
+func migrateIngressBackend(backend map[string]interface{}) map[string]interface{} {
+	serviceName, ok := backend["serviceName"]
+	if !ok {
+		return backend
+	}
+	port := make(map[string]interface{})
+	switch servicePort := backend["servicePort"].(type) {
+	case string:
+		port["name"] = servicePort
+	case nil:
+	default:
+		port["number"] = servicePort
+	}
+	delete(backend, "serviceName")
+	delete(backend, "servicePort")
+	backend["service"] = map[string]interface{}{
+		"name": serviceName,
+		"port": port,
+	}
+	return backend
+}
+
+// The autoscaling/v2 metrics moved their names under `metric` and their
+// targets under `target`, along with the type of the target
+func migrateHorizontalPodAutoscaler(o Object) error {
+	metrics := o.Lookup([]string{"spec", "metrics"})
+	if len(metrics) == 0 {
+		return nil
+	}
+	metricList, _ := metrics[0].([]interface{})
+	for _, metric := range metricList {
+		metric, ok := metric.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, source := range []string{"resource", "pods", "object", "external"} {
+			if value, ok := metric[source].(map[string]interface{}); ok {
+				metric[source] = migrateMetricSource(value)
+			}
+		}
+	}
+	return nil
+}
+
+func migrateMetricSource(source map[string]interface{}) map[string]interface{} {
+	migrated := make(map[string]interface{})
+	if name, ok := source["name"]; ok {
+		migrated["name"] = name
+	}
+	if reference, ok := source["target"].(map[string]interface{}); ok {
+		migrated["describedObject"] = reference
+	}
+
+	if name, ok := source["metricName"]; ok {
+		metric := map[string]interface{}{"name": name}
+		for _, field := range []string{"selector", "metricSelector"} {
+			if selector, ok := source[field]; ok {
+				metric["selector"] = selector
+			}
+		}
+		migrated["metric"] = metric
+	}
+
+	switch {
+	case source["targetAverageUtilization"] != nil:
+		migrated["target"] = map[string]interface{}{
+			"type":               "Utilization",
+			"averageUtilization": source["targetAverageUtilization"],
+		}
+	case source["targetAverageValue"] != nil:
+		migrated["target"] = map[string]interface{}{
+			"type":         "AverageValue",
+			"averageValue": source["targetAverageValue"],
+		}
+	case source["averageValue"] != nil:
+		migrated["target"] = map[string]interface{}{
+			"type":         "AverageValue",
+			"averageValue": source["averageValue"],
+		}
+	case source["targetValue"] != nil:
+		migrated["target"] = map[string]interface{}{
+			"type":  "Value",
+			"value": source["targetValue"],
+		}
+	}
+	return migrated
+}
+
+// The admissionregistration.k8s.io/v1 webhooks require `sideEffects` to be
+// None or NoneOnDryRun, and `admissionReviewVersions` to be set. The
+// defaults which changed between versions are set to their former value.
+func migrateWebhooks(o Object) error {
+	webhooks, _ := o["webhooks"].([]interface{})
+	for _, webhook := range webhooks {
+		webhook, ok := webhook.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch sideEffects := webhook["sideEffects"]; sideEffects {
+		case "None", "NoneOnDryRun":
+		case nil:
+			return fmt.Errorf("webhook %q must set sideEffects to None or "+
+				"NoneOnDryRun", webhook["name"])
+		default:
+			return fmt.Errorf("webhook %q has sideEffects %v, which must "+
+				"be None or NoneOnDryRun", webhook["name"], sideEffects)
+		}
+
+		defaults := map[string]interface{}{
+			"admissionReviewVersions": []interface{}{"v1beta1"},
+			"failurePolicy":           "Ignore",
+			"matchPolicy":             "Exact",
+			"timeoutSeconds":          30,
+		}
+		for field, value := range defaults {
+			if _, ok := webhook[field]; !ok {
+				webhook[field] = value
+			}
+		}
+	}
+	return nil
+}
+
+// The apiextensions.k8s.io/v1 custom resource definitions require a
+// structural schema for every version, which cannot be derived from the
+// v1beta1 validation
+func migrateCustomResourceDefinition(o Object) error {
+	return fmt.Errorf("the definition must be rewritten with a structural " +
+		"schema for every version")
+}
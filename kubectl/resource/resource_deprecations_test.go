@@ -0,0 +1,204 @@
+package resource_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+)
+
+var _ = Describe("ResourceDeprecations", func() {
+
+	Describe("ConvertDeprecatedAPI", func() {
+
+		It("Should add the selector of apps/v1 workloads", func() {
+			obj, err := DecodeObject("apiVersion: extensions/v1beta1\n" +
+				"kind: Deployment\n" +
+				"metadata:\n" +
+				"  name: nginx\n" +
+				"spec:\n" +
+				"  template:\n" +
+				"    metadata:\n" +
+				"      labels:\n" +
+				"        app: nginx\n")
+			Expect(err).To(BeNil())
+
+			apiVersion, err := obj.ConvertDeprecatedAPI(
+				map[string]bool{"apps/v1": true})
+			Expect(err).To(BeNil())
+			Expect(apiVersion).To(Equal("apps/v1"))
+			Expect(obj.APIVersion()).To(Equal("apps/v1"))
+
+			selector, _ := ParseFieldPath("spec.selector.matchLabels.app")
+			Expect(obj.Lookup(selector)).To(Equal([]interface{}{"nginx"}))
+		})
+
+		It("Should migrate the backends and paths of ingresses", func() {
+			obj, err := DecodeObject(`{"apiVersion": "extensions/v1beta1",
+				"kind": "Ingress", "metadata": {"name": "web"}, "spec": {
+				"backend": {"serviceName": "default", "servicePort": "http"},
+				"rules": [{"http": {"paths": [{"path": "/",
+					"backend": {"serviceName": "web", "servicePort": 80}}]}}]}}`)
+			Expect(err).To(BeNil())
+
+			apiVersion, err := obj.ConvertDeprecatedAPI(map[string]bool{
+				"networking.k8s.io/v1": true, "extensions/v1beta1": true})
+			Expect(err).To(BeNil())
+			Expect(apiVersion).To(Equal("networking.k8s.io/v1"))
+
+			value, _ := obj.EvaluateJSONPath("spec.defaultBackend.service.port.name")
+			Expect(value).To(Equal("http"))
+			value, _ = obj.EvaluateJSONPath(
+				"spec.rules[0].http.paths[0].backend.service.port.number")
+			Expect(value).To(Equal("80"))
+			value, _ = obj.EvaluateJSONPath("spec.rules[0].http.paths[0].pathType")
+			Expect(value).To(Equal("ImplementationSpecific"))
+		})
+
+		It("Should fall back to the next served replacement", func() {
+			obj, err := DecodeObject(`{"apiVersion": "extensions/v1beta1",
+				"kind": "Ingress", "metadata": {"name": "web"}, "spec": {
+				"backend": {"serviceName": "web", "servicePort": 80}}}`)
+			Expect(err).To(BeNil())
+
+			apiVersion, err := obj.ConvertDeprecatedAPI(
+				map[string]bool{"networking.k8s.io/v1beta1": true})
+			Expect(err).To(BeNil())
+			Expect(apiVersion).To(Equal("networking.k8s.io/v1beta1"))
+
+			value, _ := obj.EvaluateJSONPath("spec.backend.serviceName")
+			Expect(value).To(Equal("web"))
+		})
+
+		It("Should leave objects untouched when no replacement is served", func() {
+			obj, err := DecodeObject(`{"apiVersion": "batch/v1beta1",
+				"kind": "CronJob", "metadata": {"name": "backup"}}`)
+			Expect(err).To(BeNil())
+
+			apiVersion, err := obj.ConvertDeprecatedAPI(
+				map[string]bool{"batch/v1beta1": true})
+			Expect(err).To(BeNil())
+			Expect(apiVersion).To(Equal(""))
+			Expect(obj.APIVersion()).To(Equal("batch/v1beta1"))
+		})
+
+		It("Should fail when neither the api version nor a replacement is served", func() {
+			obj, err := DecodeObject(`{"apiVersion": "extensions/v1beta1",
+				"kind": "PodSecurityPolicy", "metadata": {"name": "restricted"}}`)
+			Expect(err).To(BeNil())
+
+			_, err = obj.ConvertDeprecatedAPI(map[string]bool{"apps/v1": true})
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("Should migrate the metrics of horizontal pod autoscalers", func() {
+			obj, err := DecodeObject(`{"apiVersion": "autoscaling/v2beta1",
+				"kind": "HorizontalPodAutoscaler", "metadata": {"name": "web"},
+				"spec": {"metrics": [
+					{"type": "Resource", "resource": {"name": "cpu",
+						"targetAverageUtilization": 80}},
+					{"type": "Pods", "pods": {"metricName": "requests",
+						"targetAverageValue": "10"}},
+					{"type": "External", "external": {"metricName": "queue",
+						"targetValue": "100"}}]}}`)
+			Expect(err).To(BeNil())
+
+			apiVersion, err := obj.ConvertDeprecatedAPI(
+				map[string]bool{"autoscaling/v2": true})
+			Expect(err).To(BeNil())
+			Expect(apiVersion).To(Equal("autoscaling/v2"))
+
+			expected := map[string]string{
+				"spec.metrics[0].resource.name":                      "cpu",
+				"spec.metrics[0].resource.target.type":               "Utilization",
+				"spec.metrics[0].resource.target.averageUtilization": "80",
+				"spec.metrics[1].pods.metric.name":                   "requests",
+				"spec.metrics[1].pods.target.type":                   "AverageValue",
+				"spec.metrics[1].pods.target.averageValue":           "10",
+				"spec.metrics[2].external.metric.name":               "queue",
+				"spec.metrics[2].external.target.type":               "Value",
+				"spec.metrics[2].external.target.value":              "100",
+			}
+			for path, value := range expected {
+				actual, _ := obj.EvaluateJSONPath(path)
+				Expect(actual).To(Equal(value), path)
+			}
+			value, _ := obj.EvaluateJSONPath("spec.metrics[0].resource.targetAverageUtilization")
+			Expect(value).To(Equal(""))
+		})
+
+		It("Should keep the former defaults of admission webhooks", func() {
+			obj, err := DecodeObject(`{"apiVersion": "admissionregistration.k8s.io/v1beta1",
+				"kind": "ValidatingWebhookConfiguration", "metadata": {"name": "policy"},
+				"webhooks": [{"name": "policy.example.com", "sideEffects": "None",
+					"timeoutSeconds": 5}]}`)
+			Expect(err).To(BeNil())
+
+			apiVersion, err := obj.ConvertDeprecatedAPI(
+				map[string]bool{"admissionregistration.k8s.io/v1": true})
+			Expect(err).To(BeNil())
+			Expect(apiVersion).To(Equal("admissionregistration.k8s.io/v1"))
+
+			expected := map[string]string{
+				"webhooks[0].admissionReviewVersions[0]": "v1beta1",
+				"webhooks[0].failurePolicy":              "Ignore",
+				"webhooks[0].matchPolicy":                "Exact",
+				"webhooks[0].timeoutSeconds":             "5",
+			}
+			for path, value := range expected {
+				actual, _ := obj.EvaluateJSONPath(path)
+				Expect(actual).To(Equal(value), path)
+			}
+		})
+
+		It("Should fail to convert webhooks with unknown side effects", func() {
+			webhook := `{"apiVersion": "admissionregistration.k8s.io/v1beta1",
+				"kind": "MutatingWebhookConfiguration", "metadata": {"name": "inject"},
+				"webhooks": [{"name": "inject.example.com"}]}`
+			obj, err := DecodeObject(webhook)
+			Expect(err).To(BeNil())
+
+			_, err = obj.ConvertDeprecatedAPI(
+				map[string]bool{"admissionregistration.k8s.io/v1": true})
+			Expect(err).NotTo(BeNil())
+			Expect(err.Error()).To(ContainSubstring("sideEffects"))
+			Expect(obj.APIVersion()).To(Equal("admissionregistration.k8s.io/v1beta1"))
+
+			apiVersion, err := obj.ConvertDeprecatedAPI(map[string]bool{
+				"admissionregistration.k8s.io/v1":      true,
+				"admissionregistration.k8s.io/v1beta1": true})
+			Expect(err).To(BeNil())
+			Expect(apiVersion).To(Equal(""))
+			Expect(obj.APIVersion()).To(Equal("admissionregistration.k8s.io/v1beta1"))
+		})
+
+		It("Should refuse to convert removed custom resource definitions", func() {
+			obj, err := DecodeObject(`{"apiVersion": "apiextensions.k8s.io/v1beta1",
+				"kind": "CustomResourceDefinition",
+				"metadata": {"name": "crontabs.example.com"},
+				"spec": {"group": "example.com", "version": "v1"}}`)
+			Expect(err).To(BeNil())
+
+			_, err = obj.ConvertDeprecatedAPI(
+				map[string]bool{"apiextensions.k8s.io/v1": true})
+			Expect(err).NotTo(BeNil())
+			Expect(err.Error()).To(ContainSubstring("removed api version"))
+			Expect(obj.APIVersion()).To(Equal("apiextensions.k8s.io/v1beta1"))
+
+			apiVersion, err := obj.ConvertDeprecatedAPI(map[string]bool{
+				"apiextensions.k8s.io/v1": true, "apiextensions.k8s.io/v1beta1": true})
+			Expect(err).To(BeNil())
+			Expect(apiVersion).To(Equal(""))
+		})
+
+		It("Should ignore current api versions", func() {
+			obj, err := DecodeObject(`{"apiVersion": "v1", "kind": "Service",
+				"metadata": {"name": "web"}}`)
+			Expect(err).To(BeNil())
+
+			apiVersion, err := obj.ConvertDeprecatedAPI(map[string]bool{})
+			Expect(err).To(BeNil())
+			Expect(apiVersion).To(Equal(""))
+		})
+	})
+})
@@ -0,0 +1,114 @@
+package resource
+
+import (
+	"bufio"
+	"strings"
+)
+
+// APIResource describes a resource type served by the cluster, as listed by
+// `kubectl api-resources -o wide`.
+type APIResource struct {
+	Name       string
+	ShortNames []string
+	// group/version for recent kubectl releases, group only for older ones
+	APIVersion string
+	Namespaced bool
+	Kind       string
+	Verbs      []string
+}
+
+// ParseAPIResources parses the tabular output of `kubectl api-resources`.
+//
+// The columns are located through the offsets of the header names, since
+// the short names and the api group of core resources are left empty.
+func ParseAPIResources(output string) []APIResource {
+	resources := make([]APIResource, 0)
+	scanner := bufio.NewScanner(strings.NewReader(output))
+
+	var header string
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) != "" {
+			header = scanner.Text()
+			break
+		}
+	}
+	if header == "" {
+		return resources
+	}
+
+	columns := headerColumns(header)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		values := make(map[string]string)
+		for i, column := range columns {
+			end := len(line)
+			if i+1 < len(columns) && columns[i+1].offset < end {
+				end = columns[i+1].offset
+			}
+			if column.offset >= end {
+				continue
+			}
+			values[column.name] = strings.TrimSpace(line[column.offset:end])
+		}
+
+		apiResource := APIResource{
+			Name:       values["NAME"],
+			ShortNames: splitList(values["SHORTNAMES"], ","),
+			APIVersion: values["APIVERSION"],
+			Namespaced: values["NAMESPACED"] == "true",
+			Kind:       values["KIND"],
+			Verbs: splitList(strings.Trim(values["VERBS"], "[]"),
+				" "),
+		}
+		if apiResource.APIVersion == "" {
+			apiResource.APIVersion = values["APIGROUP"]
+		}
+		resources = append(resources, apiResource)
+	}
+	return resources
+}
+
+// Group returns the api group of the resource, empty for the core group.
+func (r APIResource) Group() string {
+	if !strings.Contains(r.APIVersion, "/") {
+		if r.APIVersion == "v1" {
+			return ""
+		}
+		return r.APIVersion
+	}
+	return strings.SplitN(r.APIVersion, "/", 2)[0]
+}
+
+type headerColumn struct {
+	name   string
+	offset int
+}
+
+func headerColumns(header string) []headerColumn {
+	columns := make([]headerColumn, 0)
+	for i := 0; i < len(header); i++ {
+		if header[i] == ' ' || (i > 0 && header[i-1] != ' ') {
+			continue
+		}
+		end := strings.IndexByte(header[i:], ' ')
+		if end < 0 {
+			end = len(header) - i
+		}
+		columns = append(columns,
+			headerColumn{name: header[i : i+end], offset: i})
+	}
+	return columns
+}
+
+func splitList(value, separator string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, separator) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,53 @@
+package resource_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+)
+
+var _ = Describe("ResourceDiscovery", func() {
+
+	Describe("ParseAPIResources", func() {
+
+		It("Should parse resources with empty columns", func() {
+
+			const output = ("NAME          SHORTNAMES   APIVERSION   NAMESPACED   KIND         VERBS\n" +
+				"bindings                   v1           true         Binding      [create]\n" +
+				"namespaces    ns           v1           false        Namespace    [create delete get list]\n" +
+				"deployments   deploy       apps/v1      true         Deployment   [get list]\n")
+
+			resources := ParseAPIResources(output)
+			Expect(len(resources)).To(Equal(3))
+
+			Expect(resources[0].Name).To(Equal("bindings"))
+			Expect(resources[0].ShortNames).To(BeEmpty())
+			Expect(resources[0].Group()).To(Equal(""))
+
+			Expect(resources[1].Kind).To(Equal("Namespace"))
+			Expect(resources[1].Namespaced).To(BeFalse())
+			Expect(resources[1].Verbs).To(Equal(
+				[]string{"create", "delete", "get", "list"}))
+
+			Expect(resources[2].APIVersion).To(Equal("apps/v1"))
+			Expect(resources[2].Group()).To(Equal("apps"))
+			Expect(resources[2].Namespaced).To(BeTrue())
+		})
+
+		It("Should parse the api group column of older kubectl releases", func() {
+
+			const output = ("NAME          SHORTNAMES   APIGROUP   NAMESPACED   KIND         VERBS\n" +
+				"deployments   deploy       apps       true         Deployment   [get list]\n")
+
+			resources := ParseAPIResources(output)
+			Expect(len(resources)).To(Equal(1))
+			Expect(resources[0].APIVersion).To(Equal("apps"))
+			Expect(resources[0].Group()).To(Equal("apps"))
+		})
+
+		It("Should return no resources for an empty output", func() {
+			Expect(ParseAPIResources("")).To(BeEmpty())
+		})
+	})
+})
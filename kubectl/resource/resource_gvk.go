@@ -0,0 +1,86 @@
+package resource
+
+import (
+	"fmt"
+	"strings"
+)
+
+// GroupVersionKind identifies a resource type, the group being empty for
+// the core api group.
+type GroupVersionKind struct {
+	Group   string
+	Version string
+	Kind    string
+}
+
+// ParseGroupVersionKind parses the `<group>/<version>/<kind>` notation used
+// by `kubectl apply --prune-whitelist`, where the core group is spelled
+// `core`. The `<version>/<kind>` short form is accepted for the core group.
+func ParseGroupVersionKind(gvk string) (GroupVersionKind, error) {
+	parts := strings.Split(gvk, "/")
+	switch len(parts) {
+	case 2:
+		parts = append([]string{""}, parts...)
+	case 3:
+		if parts[0] == "core" {
+			parts[0] = ""
+		}
+	default:
+		return GroupVersionKind{}, fmt.Errorf(
+			"invalid group/version/kind %q", gvk)
+	}
+	if parts[1] == "" || parts[2] == "" {
+		return GroupVersionKind{}, fmt.Errorf(
+			"invalid group/version/kind %q", gvk)
+	}
+	return GroupVersionKind{Group: parts[0], Version: parts[1],
+		Kind: parts[2]}, nil
+}
+
+// GroupVersionKind returns the resource type of the object.
+func (o Object) GroupVersionKind() GroupVersionKind {
+	gvk := GroupVersionKind{Kind: o.Kind()}
+	parts := strings.SplitN(o.APIVersion(), "/", 2)
+	if len(parts) == 2 {
+		gvk.Group, gvk.Version = parts[0], parts[1]
+	} else {
+		gvk.Version = parts[0]
+	}
+	return gvk
+}
+
+func (g GroupVersionKind) String() string {
+	group := g.Group
+	if group == "" {
+		group = "core"
+	}
+	return group + "/" + g.Version + "/" + g.Kind
+}
+
+// APIVersion returns the `apiVersion` of objects of this type.
+func (g GroupVersionKind) APIVersion() string {
+	if g.Group == "" {
+		return g.Version
+	}
+	return g.Group + "/" + g.Version
+}
+
+// ResourceArg returns the fully qualified resource type argument accepted by
+// `kubectl get`, i.e. `deployment.v1.apps`.
+func (g GroupVersionKind) ResourceArg() string {
+	kind := strings.ToLower(g.Kind)
+	if g.Group == "" {
+		return kind
+	}
+	return kind + "." + g.Version + "." + g.Group
+}
+
+// ResourceTypeArg returns the resource type argument accepted by `kubectl get`
+// for a kind, fully qualified when the api version is known.
+func ResourceTypeArg(apiVersion, kind string) string {
+	if apiVersion == "" {
+		return strings.ToLower(kind)
+	}
+	return Object{"apiVersion": apiVersion, "kind": kind}.GroupVersionKind().
+		ResourceArg()
+}
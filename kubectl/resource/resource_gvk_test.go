@@ -0,0 +1,41 @@
+package resource_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+)
+
+var _ = Describe("GroupVersionKind", func() {
+
+	It("Should parse the kubectl prune notation", func() {
+		gvk, err := ParseGroupVersionKind("apps/v1/Deployment")
+		Expect(err).To(BeNil())
+		Expect(gvk).To(Equal(GroupVersionKind{Group: "apps", Version: "v1",
+			Kind: "Deployment"}))
+		Expect(gvk.ResourceArg()).To(Equal("deployment.v1.apps"))
+
+		gvk, err = ParseGroupVersionKind("core/v1/ConfigMap")
+		Expect(err).To(BeNil())
+		Expect(gvk.Group).To(Equal(""))
+		Expect(gvk.ResourceArg()).To(Equal("configmap"))
+		Expect(gvk.String()).To(Equal("core/v1/ConfigMap"))
+	})
+
+	It("Should reject invalid notations", func() {
+		_, err := ParseGroupVersionKind("Deployment")
+		Expect(err).NotTo(BeNil())
+
+		_, err = ParseGroupVersionKind("apps//Deployment")
+		Expect(err).NotTo(BeNil())
+	})
+
+	It("Should return the type of an object", func() {
+		obj := Object{"apiVersion": "v1", "kind": "Secret"}
+		Expect(obj.GroupVersionKind().String()).To(Equal("core/v1/Secret"))
+
+		obj = Object{"apiVersion": "batch/v1", "kind": "Job"}
+		Expect(obj.GroupVersionKind().APIVersion()).To(Equal("batch/v1"))
+	})
+})
@@ -0,0 +1,105 @@
+package resource
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+
+	// Job names are used as label values on their pods
+	maxJobNameLength = 63
+)
+
+// NewJobRun builds a run of a Job template, named after the name (or
+// generateName) of the template and the suffix. The run is deleted by the
+// TTL controller ttl seconds after it finishes, unless ttl is negative.
+func NewJobRun(template Object, suffix string, ttl int) (Object, error) {
+	if template.Kind() != "Job" {
+		return nil, fmt.Errorf("expecting a Job template, found %q",
+			template.Kind())
+	}
+
+	job := template.Copy()
+	metadata := job.ensureMetadata()
+	base := job.Name()
+	if base == "" {
+		generateName, _ := metadata["generateName"].(string)
+		base = strings.TrimSuffix(generateName, "-")
+	}
+	if base == "" {
+		return nil, fmt.Errorf("the Job template must set a name or a generateName")
+	}
+	if maxLength := maxJobNameLength - len(suffix) - 1; len(base) > maxLength {
+		base = strings.TrimRight(base[:maxLength], "-.")
+	}
+	delete(metadata, "generateName")
+	metadata["name"] = base + "-" + suffix
+
+	if ttl >= 0 {
+		spec, ok := job["spec"].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("the Job template must set a spec")
+		}
+		spec["ttlSecondsAfterFinished"] = ttl
+	}
+	return job, nil
+}
+
+// JobStatus returns whether a Job is running, succeeded or failed, along with
+// the message of its Failed condition
+func (o Object) JobStatus() (status, message string) {
+	for _, condition := range o.Lookup([]string{"status", "conditions", "*"}) {
+		condition, ok := condition.(map[string]interface{})
+		if !ok || condition["status"] != "True" {
+			continue
+		}
+		switch condition["type"] {
+		case "Complete":
+			return JobSucceeded, ""
+		case "Failed":
+			message, _ := condition["message"].(string)
+			return JobFailed, message
+		}
+	}
+	return JobRunning, ""
+}
+
+// LatestPod returns the most recently created pod of a list, nil when the
+// list is empty
+func LatestPod(pods []Object) Object {
+	var latest Object
+	latestCreation := ""
+	for _, pod := range pods {
+		// RFC 3339 timestamps of the api server sort lexicographically
+		creation, _ := pod.metadata()["creationTimestamp"].(string)
+		if latest == nil || creation > latestCreation {
+			latest, latestCreation = pod, creation
+		}
+	}
+	return latest
+}
+
+// PodExitCode returns the exit code of the first container of a pod which
+// failed, or 0 when every container succeeded. It returns false when no
+// container has terminated.
+func (o Object) PodExitCode() (int, bool) {
+	terminated := false
+	for _, state := range o.Lookup([]string{"status", "containerStatuses",
+		"*", "state", "terminated"}) {
+
+		state, ok := state.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		terminated = true
+		// decoded from JSON
+		if exitCode, ok := state["exitCode"].(float64); ok && exitCode != 0 {
+			return int(exitCode), true
+		}
+	}
+	return 0, terminated
+}
@@ -0,0 +1,92 @@
+package resource_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+)
+
+var _ = Describe("ResourceJob", func() {
+
+	const template = `
+apiVersion: batch/v1
+kind: Job
+metadata:
+  generateName: migrate-
+spec:
+  template:
+    spec:
+      restartPolicy: Never
+      containers:
+      - name: migrate
+        image: app:1.2.0
+`
+
+	Describe("NewJobRun", func() {
+
+		It("Should name the run after the template", func() {
+			obj, _ := DecodeObject(template)
+			job, err := NewJobRun(obj, "a1b2c3d4", 600)
+			Expect(err).To(BeNil())
+
+			Expect(job.Name()).To(Equal("migrate-a1b2c3d4"))
+			value, _ := job.EvaluateJSONPath("{.spec.ttlSecondsAfterFinished}")
+			Expect(value).To(Equal("600"))
+			Expect(obj.Name()).To(Equal(""))
+		})
+
+		It("Should keep the run when the ttl is negative", func() {
+			obj, _ := DecodeObject(template)
+			job, err := NewJobRun(obj, "a1b2c3d4", -1)
+			Expect(err).To(BeNil())
+
+			value, _ := job.EvaluateJSONPath("{.spec.ttlSecondsAfterFinished}")
+			Expect(value).To(Equal(""))
+		})
+
+		It("Should reject other kinds", func() {
+			obj, _ := DecodeObject("apiVersion: v1\nkind: Pod\n" +
+				"metadata:\n  name: migrate\n")
+			_, err := NewJobRun(obj, "a1b2c3d4", -1)
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
+	Describe("JobStatus", func() {
+
+		It("Should report failed jobs", func() {
+			job, _ := DecodeObject(`{"kind": "Job", "status": {"conditions": [
+				{"type": "Failed", "status": "True",
+				 "message": "Job has reached the specified backoff limit"}]}}`)
+
+			status, message := job.JobStatus()
+			Expect(status).To(Equal(JobFailed))
+			Expect(message).To(ContainSubstring("backoff limit"))
+		})
+
+		It("Should report running jobs", func() {
+			job, _ := DecodeObject(`{"kind": "Job", "status": {"active": 1}}`)
+
+			status, _ := job.JobStatus()
+			Expect(status).To(Equal(JobRunning))
+		})
+	})
+
+	Describe("PodExitCode", func() {
+
+		It("Should return the exit code of the latest pod", func() {
+			first, _ := DecodeObject(`{"kind": "Pod", "metadata": {
+				"creationTimestamp": "2024-05-02T09:41:07Z"}}`)
+			latest, _ := DecodeObject(`{"kind": "Pod", "metadata": {
+				"creationTimestamp": "2024-05-02T09:43:12Z"}, "status": {
+				"containerStatuses": [{"state": {"terminated": {"exitCode": 3}}}]}}`)
+
+			exitCode, ok := LatestPod([]Object{first, latest}).PodExitCode()
+			Expect(ok).To(BeTrue())
+			Expect(exitCode).To(Equal(3))
+			_, ok = first.PodExitCode()
+			Expect(ok).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,620 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	yamlReader "github.com/kubernetes/apimachinery/pkg/util/yaml"
+)
+
+// Object is a decoded kubernetes document, as returned by `kubectl get -o json`
+// or produced by decoding a manifest document.
+type Object map[string]interface{}
+
+// DecodeObject decodes a single YAML or JSON manifest document.
+func DecodeObject(document string) (Object, error) {
+	jsonDoc, err := yamlReader.ToJSON([]byte(document))
+	if err != nil {
+		return nil, err
+	}
+	obj := Object{}
+	if err := json.Unmarshal(jsonDoc, &obj); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// DecodeObjects decodes the output of `kubectl get -o json`, which is either
+// a single object or a list of objects.
+func DecodeObjects(output string) ([]Object, error) {
+	if strings.TrimSpace(output) == "" {
+		return []Object{}, nil
+	}
+	obj, err := DecodeObject(output)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(obj.Kind(), "List") {
+		return []Object{obj}, nil
+	}
+
+	items, _ := obj["items"].([]interface{})
+	objects := make([]Object, 0, len(items))
+	for _, item := range items {
+		if itemMap, ok := item.(map[string]interface{}); ok {
+			objects = append(objects, Object(itemMap))
+		}
+	}
+	return objects, nil
+}
+
+// Encode renders the object back into a YAML manifest document.
+func (o Object) Encode() (string, error) {
+	out, err := yaml.Marshal(map[string]interface{}(o))
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// Copy returns a deep copy of the object.
+func (o Object) Copy() Object {
+	return deepCopy(map[string]interface{}(o)).(map[string]interface{})
+}
+
+func (o Object) Kind() string {
+	kind, _ := o["kind"].(string)
+	return kind
+}
+
+func (o Object) APIVersion() string {
+	apiVersion, _ := o["apiVersion"].(string)
+	return apiVersion
+}
+
+func (o Object) metadata() map[string]interface{} {
+	metadata, _ := o["metadata"].(map[string]interface{})
+	return metadata
+}
+
+func (o Object) Name() string {
+	name, _ := o.metadata()["name"].(string)
+	return name
+}
+
+func (o Object) Namespace() string {
+	namespace, _ := o.metadata()["namespace"].(string)
+	return namespace
+}
+
+func (o Object) UID() string {
+	uid, _ := o.metadata()["uid"].(string)
+	return uid
+}
+
+func (o Object) SetNamespace(namespace string) {
+	o.ensureMetadata()["namespace"] = namespace
+}
+
+func (o Object) Labels() map[string]string {
+	return stringMap(o.metadata()["labels"])
+}
+
+func (o Object) Annotations() map[string]string {
+	return stringMap(o.metadata()["annotations"])
+}
+
+func (o Object) SetLabel(key, value string) {
+	setStringMapEntry(o.ensureMetadata(), "labels", key, value)
+}
+
+func (o Object) SetAnnotation(key, value string) {
+	setStringMapEntry(o.ensureMetadata(), "annotations", key, value)
+}
+
+func stringMap(value interface{}) map[string]string {
+	strs := make(map[string]string)
+	if m, ok := value.(map[string]interface{}); ok {
+		for k, v := range m {
+			if str, ok := v.(string); ok {
+				strs[k] = str
+			}
+		}
+	}
+	return strs
+}
+
+func setStringMapEntry(parent map[string]interface{}, key, entryKey,
+	entryValue string) {
+
+	entries, ok := parent[key].(map[string]interface{})
+	if !ok {
+		entries = make(map[string]interface{})
+		parent[key] = entries
+	}
+	entries[entryKey] = entryValue
+}
+
+func (o Object) ensureMetadata() map[string]interface{} {
+	metadata := o.metadata()
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+		o["metadata"] = metadata
+	}
+	return metadata
+}
+
+var podTemplatePaths = map[string][]string{
+	"Deployment":            {"spec", "template"},
+	"StatefulSet":           {"spec", "template"},
+	"DaemonSet":             {"spec", "template"},
+	"ReplicaSet":            {"spec", "template"},
+	"ReplicationController": {"spec", "template"},
+	"Job":                   {"spec", "template"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template"},
+}
+
+// MergeMetadata adds the given labels and annotations to the object metadata.
+// Values already set in the document take precedence.
+func (o Object) MergeMetadata(labels, annotations map[string]string) {
+	mergeMetadata(o.ensureMetadata(), labels, annotations)
+}
+
+// MergePodTemplateMetadata adds the given labels and annotations to the pod
+// templates of workload kinds (Deployment, StatefulSet, Job, CronJob ...).
+func (o Object) MergePodTemplateMetadata(labels, annotations map[string]string) {
+	templatePath, ok := podTemplatePaths[o.Kind()]
+	if !ok {
+		return
+	}
+	for _, template := range o.Lookup(templatePath) {
+		templateMap, ok := template.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		metadata, ok := templateMap["metadata"].(map[string]interface{})
+		if !ok {
+			metadata = make(map[string]interface{})
+			templateMap["metadata"] = metadata
+		}
+		mergeMetadata(metadata, labels, annotations)
+	}
+}
+
+func mergeMetadata(metadata map[string]interface{},
+	labels, annotations map[string]string) {
+
+	mergeStringMap(metadata, "labels", labels)
+	mergeStringMap(metadata, "annotations", annotations)
+}
+
+func mergeStringMap(parent map[string]interface{}, key string,
+	values map[string]string) {
+
+	if len(values) == 0 {
+		return
+	}
+	existing, ok := parent[key].(map[string]interface{})
+	if !ok {
+		existing = make(map[string]interface{})
+		parent[key] = existing
+	}
+	for k, v := range values {
+		if _, ok := existing[k]; !ok {
+			existing[k] = v
+		}
+	}
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(typed))
+		for k, v := range typed {
+			out[k] = deepCopy(v)
+		}
+		return out
+	case Object:
+		return deepCopy(map[string]interface{}(typed))
+	case []interface{}:
+		out := make([]interface{}, len(typed))
+		for i, v := range typed {
+			out[i] = deepCopy(v)
+		}
+		return out
+	default:
+		return typed
+	}
+}
+
+// serverFields are populated by the api server and rejected or ignored when
+// applying an object
+var serverFields = [][]string{
+	{"status"},
+	{"metadata", "uid"},
+	{"metadata", "selfLink"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "generation"},
+	{"metadata", "creationTimestamp"},
+	{"metadata", "managedFields"},
+	{"metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration"},
+}
+
+// StripServerFields removes the fields populated by the api server, so that
+// a live object can be applied again.
+func (o Object) StripServerFields() {
+	for _, field := range serverFields {
+		o.RemoveField(field)
+	}
+}
+
+// ParseFieldPath splits a field path into its segments. Both the dotted form
+// (`spec.replicas`) and the JSONPath form (`$.spec.template.spec.containers[0]`,
+// `{.metadata.annotations['cert-manager.io/inject-ca-from']}`) are accepted.
+// A `*` segment matches every key of a map or every element of a list.
+func ParseFieldPath(path string) ([]string, error) {
+	path = strings.TrimSpace(path)
+	path = strings.TrimSuffix(strings.TrimPrefix(path, "{"), "}")
+	path = strings.TrimPrefix(path, "$")
+	path = strings.TrimPrefix(path, ".")
+
+	segments := make([]string, 0)
+	current := &strings.Builder{}
+	flush := func() {
+		if current.Len() > 0 {
+			segments = append(segments, current.String())
+			current.Reset()
+		}
+	}
+
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '.':
+			flush()
+		case '[':
+			flush()
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated '[' in field path %q", path)
+			}
+			segment := strings.Trim(path[i+1:i+end], `'"`)
+			if segment == "" {
+				return nil, fmt.Errorf("empty '[]' in field path %q", path)
+			}
+			segments = append(segments, segment)
+			i += end
+		default:
+			current.WriteByte(path[i])
+		}
+	}
+	flush()
+
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("empty field path")
+	}
+	return segments, nil
+}
+
+// RemoveField deletes every value matching the given path segments from the
+// object. Missing intermediate fields are silently ignored.
+func (o Object) RemoveField(segments []string) {
+	removeField(map[string]interface{}(o), segments)
+}
+
+func removeField(value interface{}, segments []string) {
+	if len(segments) == 0 {
+		return
+	}
+	head, rest := segments[0], segments[1:]
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		if head == "*" {
+			for k, v := range typed {
+				if len(rest) == 0 {
+					delete(typed, k)
+				} else {
+					removeField(v, rest)
+				}
+			}
+			return
+		}
+		if len(rest) == 0 {
+			delete(typed, head)
+			return
+		}
+		removeField(typed[head], rest)
+	case []interface{}:
+		// elements of a list are only removed from nested values, so that
+		// the remaining indexes keep matching between two documents
+		if head == "*" {
+			for _, v := range typed {
+				removeField(v, rest)
+			}
+			return
+		}
+		index, err := strconv.Atoi(head)
+		if err != nil || index < 0 || index >= len(typed) {
+			return
+		}
+		if len(rest) == 0 {
+			typed[index] = nil
+			return
+		}
+		removeField(typed[index], rest)
+	}
+}
+
+// Lookup returns every value matching the given path segments.
+func (o Object) Lookup(segments []string) []interface{} {
+	return lookup(map[string]interface{}(o), segments)
+}
+
+func lookup(value interface{}, segments []string) []interface{} {
+	if len(segments) == 0 {
+		return []interface{}{value}
+	}
+	head, rest := segments[0], segments[1:]
+	results := make([]interface{}, 0)
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		if head == "*" {
+			for _, v := range typed {
+				results = append(results, lookup(v, rest)...)
+			}
+			return results
+		}
+		if v, ok := typed[head]; ok {
+			results = append(results, lookup(v, rest)...)
+		}
+	case []interface{}:
+		if head == "*" {
+			for _, v := range typed {
+				results = append(results, lookup(v, rest)...)
+			}
+			return results
+		}
+		if strings.HasPrefix(head, "?(") {
+			for _, v := range typed {
+				if matchFilter(v, head) {
+					results = append(results, lookup(v, rest)...)
+				}
+			}
+			return results
+		}
+		index, err := strconv.Atoi(head)
+		if err == nil && index >= 0 && index < len(typed) {
+			results = append(results, lookup(typed[index], rest)...)
+		}
+	}
+	return results
+}
+
+// Evaluates a JSONPath filter such as `?(@.kind=="Service")` against a list
+// element. Only the `==` and `!=` comparisons and existence checks (`?(@.a)`)
+// are supported.
+func matchFilter(value interface{}, filter string) bool {
+	expression := strings.TrimSuffix(strings.TrimPrefix(filter, "?("), ")")
+
+	operator := ""
+	for _, candidate := range []string{"==", "!="} {
+		if strings.Contains(expression, candidate) {
+			operator = candidate
+			break
+		}
+	}
+
+	field := expression
+	expected := ""
+	if operator != "" {
+		parts := strings.SplitN(expression, operator, 2)
+		field = strings.TrimSpace(parts[0])
+		expected = strings.Trim(strings.TrimSpace(parts[1]), `'"`)
+	}
+	segments, err := ParseFieldPath(strings.TrimPrefix(field, "@"))
+	if err != nil {
+		return false
+	}
+	values := lookup(value, segments)
+
+	switch operator {
+	case "==":
+		return len(values) > 0 && fmt.Sprint(values[0]) == expected
+	case "!=":
+		return len(values) == 0 || fmt.Sprint(values[0]) != expected
+	default:
+		return len(values) > 0
+	}
+}
+
+// IsSubset reports whether every field set in the desired object holds the
+// same value in the live object. Fields only present in the live object
+// (defaults, status, server populated metadata) are not considered drift,
+// and neither are the empty values the api server omits. Quantities are
+// compared by value, the api server canonicalizing them (`1024Mi` is stored
+// as `1Gi`, `0.5` as `500m`): the values of the quantity fields, and the
+// values carrying a unit suffix elsewhere. Other numeric strings, such as
+// versions, are compared as is.
+func IsSubset(desired, live interface{}) bool {
+	return isSubset(desired, live, false)
+}
+
+func isSubset(desired, live interface{}, quantity bool) bool {
+	if live == nil {
+		return isEmptyValue(desired)
+	}
+	switch typedDesired := desired.(type) {
+	case Object:
+		return isSubset(map[string]interface{}(typedDesired), live, quantity)
+	case map[string]interface{}:
+		if typedLive, ok := live.(Object); ok {
+			live = map[string]interface{}(typedLive)
+		}
+		typedLive, ok := live.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for k, v := range typedDesired {
+			if !isSubset(v, typedLive[k], quantity || quantityFields[k]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		typedLive, ok := live.([]interface{})
+		if !ok {
+			return false
+		}
+		if len(typedDesired) != len(typedLive) {
+			return false
+		}
+		for i := range typedDesired {
+			if !isSubset(typedDesired[i], typedLive[i], quantity) {
+				return false
+			}
+		}
+		return true
+	case nil:
+		return true
+	default:
+		if fmt.Sprint(desired) == fmt.Sprint(live) {
+			return true
+		}
+		if !quantity && !(hasUnitSuffix(desired) && hasUnitSuffix(live)) {
+			return false
+		}
+		desiredQuantity, ok := parseQuantity(desired)
+		if !ok {
+			return false
+		}
+		liveQuantity, ok := parseQuantity(live)
+		return ok && desiredQuantity.Cmp(liveQuantity) == 0
+	}
+}
+
+// Reports whether a value is empty, as omitted by the api server: a zero
+// scalar, or a map or list of empty values
+func isEmptyValue(value interface{}) bool {
+	switch typed := value.(type) {
+	case nil:
+		return true
+	case Object:
+		return isEmptyValue(map[string]interface{}(typed))
+	case map[string]interface{}:
+		for _, v := range typed {
+			if !isEmptyValue(v) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		return len(typed) == 0
+	case string:
+		return typed == ""
+	case bool:
+		return !typed
+	case float64:
+		return typed == 0
+	case int:
+		return typed == 0
+	case int64:
+		return typed == 0
+	}
+	return false
+}
+
+var (
+	// fields whose values, at any depth, are quantities: resource limits,
+	// requests and quotas, volume capacities and size limits
+	quantityFields = map[string]bool{"limits": true, "requests": true,
+		"hard": true, "capacity": true, "allocatable": true,
+		"overhead": true, "storage": true, "sizeLimit": true}
+	unitSuffixPattern = regexp.MustCompile(`[0-9](?:[KMGTPE]i|[numkMGTPE])$`)
+	quantityPattern   = regexp.MustCompile(
+		`^([+-]?(?:[0-9]+\.?[0-9]*|\.[0-9]+))([eE][+-]?[0-9]+|[KMGTPE]i|[numkMGTPE])?$`)
+	quantitySuffixes = map[string]string{
+		"n": "1/1000000000", "u": "1/1000000", "m": "1/1000", "": "1",
+		"k": "1000", "M": "1000000", "G": "1000000000",
+		"T": "1000000000000", "P": "1000000000000000",
+		"E":  "1000000000000000000",
+		"Ki": "1024", "Mi": "1048576", "Gi": "1073741824",
+		"Ti": "1099511627776", "Pi": "1125899906842624",
+		"Ei": "1152921504606846976",
+	}
+)
+
+// Reports whether a value is a string ending with a quantity unit, such as
+// `1Gi` or `500m`
+func hasUnitSuffix(value interface{}) bool {
+	text, ok := value.(string)
+	return ok && unitSuffixPattern.MatchString(text)
+}
+
+// Parses a kubernetes quantity, such as `500m`, `1Gi` or `1e3`
+func parseQuantity(value interface{}) (*big.Rat, bool) {
+	var text string
+	switch typed := value.(type) {
+	case string:
+		text = typed
+	case float64, int, int64:
+		text = fmt.Sprint(typed)
+	default:
+		return nil, false
+	}
+
+	match := quantityPattern.FindStringSubmatch(text)
+	if match == nil {
+		return nil, false
+	}
+	quantity, ok := new(big.Rat).SetString(match[1])
+	if !ok {
+		return nil, false
+	}
+	suffix := match[2]
+	if len(suffix) > 1 && (suffix[0] == 'e' || suffix[0] == 'E') &&
+		suffix[1] != 'i' {
+		exponent, ok := new(big.Rat).SetString("1" + suffix)
+		if !ok {
+			return nil, false
+		}
+		return quantity.Mul(quantity, exponent), true
+	}
+	multiplier, _ := new(big.Rat).SetString(quantitySuffixes[suffix])
+	return quantity.Mul(quantity, multiplier), true
+}
+
+// EvaluateJSONPath evaluates a JSONPath expression against the object, such
+// as `{.status.loadBalancer.ingress[0].hostname}`. Scalar results are
+// returned as is, maps and lists are rendered as JSON and multiple results
+// are separated by spaces, as `kubectl get -o jsonpath` does.
+func (o Object) EvaluateJSONPath(expression string) (string, error) {
+	segments, err := ParseFieldPath(expression)
+	if err != nil {
+		return "", err
+	}
+
+	results := make([]string, 0)
+	for _, value := range o.Lookup(segments) {
+		switch typed := value.(type) {
+		case nil:
+			continue
+		case string:
+			results = append(results, typed)
+		case map[string]interface{}, []interface{}:
+			out, err := json.Marshal(typed)
+			if err != nil {
+				return "", err
+			}
+			results = append(results, string(out))
+		default:
+			results = append(results, fmt.Sprint(typed))
+		}
+	}
+	return strings.Join(results, " "), nil
+}
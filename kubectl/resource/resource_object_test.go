@@ -0,0 +1,241 @@
+package resource_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+)
+
+var _ = Describe("ResourceObject", func() {
+
+	const manifest = ("apiVersion: apps/v1\n" +
+		"kind: Deployment\n" +
+		"metadata:\n" +
+		"  name: nginx\n" +
+		"  annotations:\n" +
+		"    cert-manager.io/inject-ca-from: default/ca\n" +
+		"spec:\n" +
+		"  replicas: 3\n" +
+		"  template:\n" +
+		"    spec:\n" +
+		"      containers:\n" +
+		"      - name: nginx\n" +
+		"        image: nginx:1.7.9\n")
+
+	Describe("ParseFieldPath", func() {
+
+		It("Should parse dotted paths", func() {
+			segments, err := ParseFieldPath("spec.replicas")
+			Expect(err).To(BeNil())
+			Expect(segments).To(Equal([]string{"spec", "replicas"}))
+		})
+
+		It("Should parse jsonpath expressions", func() {
+			segments, err := ParseFieldPath(
+				"{.metadata.annotations['cert-manager.io/inject-ca-from']}")
+			Expect(err).To(BeNil())
+			Expect(segments).To(Equal([]string{
+				"metadata", "annotations", "cert-manager.io/inject-ca-from"}))
+
+			segments, err = ParseFieldPath("$.spec.containers[0].image")
+			Expect(err).To(BeNil())
+			Expect(segments).To(Equal([]string{
+				"spec", "containers", "0", "image"}))
+		})
+
+		It("Should reject malformed paths", func() {
+			_, err := ParseFieldPath("spec.containers[0")
+			Expect(err).NotTo(BeNil())
+
+			_, err = ParseFieldPath("")
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
+	Describe("Comparing objects", func() {
+
+		var desired, live Object
+
+		BeforeEach(func() {
+			var err error
+			desired, err = DecodeObject(manifest)
+			Expect(err).To(BeNil())
+			live, err = DecodeObject(manifest)
+			Expect(err).To(BeNil())
+			live["status"] = map[string]interface{}{"replicas": 3}
+		})
+
+		It("Should not report fields only set on the live object", func() {
+			Expect(IsSubset(desired, live)).To(BeTrue())
+		})
+
+		It("Should report changed fields", func() {
+			live["spec"].(map[string]interface{})["replicas"] = 5
+			Expect(IsSubset(desired, live)).To(BeFalse())
+		})
+
+		It("Should not report empty values omitted by the api server", func() {
+			desired, _ = DecodeObject(`{"spec": {"hostNetwork": false,
+				"containers": [{"name": "app", "env": [{"name": "DEBUG", "value": ""}]}]}}`)
+			live, _ = DecodeObject(`{"spec": {
+				"containers": [{"name": "app", "env": [{"name": "DEBUG"}]}]}}`)
+			Expect(IsSubset(desired, live)).To(BeTrue())
+
+			live, _ = DecodeObject(`{"spec": {"hostNetwork": true,
+				"containers": [{"name": "app", "env": [{"name": "DEBUG"}]}]}}`)
+			Expect(IsSubset(desired, live)).To(BeFalse())
+		})
+
+		It("Should compare quantities by value", func() {
+			desired, _ = DecodeObject(`{"requests": {"memory": "1024Mi", "cpu": 0.5,
+				"storage": "1e3"}}`)
+			live, _ = DecodeObject(`{"requests": {"memory": "1Gi", "cpu": "500m",
+				"storage": "1k"}}`)
+			Expect(IsSubset(desired, live)).To(BeTrue())
+
+			live, _ = DecodeObject(`{"requests": {"memory": "1G", "cpu": "500m",
+				"storage": "1k"}}`)
+			Expect(IsSubset(desired, live)).To(BeFalse())
+		})
+
+		It("Should compare other numeric strings as is", func() {
+			desired, _ = DecodeObject(`{"env": [{"name": "VERSION", "value": "1.10"},
+				{"name": "PORT", "value": "010"}], "replicas": "3"}`)
+			live, _ = DecodeObject(`{"env": [{"name": "VERSION", "value": "1.1"},
+				{"name": "PORT", "value": "010"}], "replicas": "3"}`)
+			Expect(IsSubset(desired, live)).To(BeFalse())
+
+			live, _ = DecodeObject(`{"env": [{"name": "VERSION", "value": "1.10"},
+				{"name": "PORT", "value": "10"}], "replicas": "3"}`)
+			Expect(IsSubset(desired, live)).To(BeFalse())
+
+			live, _ = DecodeObject(`{"env": [{"name": "VERSION", "value": "1.10"},
+				{"name": "PORT", "value": "010"}], "replicas": "3.0"}`)
+			Expect(IsSubset(desired, live)).To(BeFalse())
+		})
+
+		It("Should compare values with a unit suffix as quantities", func() {
+			desired, _ = DecodeObject(`{"spec": {"size": "1024Mi"}}`)
+			live, _ = DecodeObject(`{"spec": {"size": "1Gi"}}`)
+			Expect(IsSubset(desired, live)).To(BeTrue())
+
+			live, _ = DecodeObject(`{"spec": {"size": "1073741824"}}`)
+			Expect(IsSubset(desired, live)).To(BeFalse())
+		})
+
+		It("Should not report removed fields", func() {
+			live["spec"].(map[string]interface{})["replicas"] = 5
+			segments, _ := ParseFieldPath("spec.replicas")
+			desired.RemoveField(segments)
+			live.RemoveField(segments)
+			Expect(IsSubset(desired, live)).To(BeTrue())
+		})
+
+		It("Should remove fields through wildcards", func() {
+			segments, _ := ParseFieldPath("spec.template.spec.containers[*].image")
+			live.RemoveField(segments)
+			Expect(live.Lookup(segments)).To(BeEmpty())
+			Expect(desired.Lookup(segments)).To(Equal([]interface{}{"nginx:1.7.9"}))
+		})
+	})
+
+	Describe("Merging metadata", func() {
+
+		var obj Object
+
+		BeforeEach(func() {
+			var err error
+			obj, err = DecodeObject(manifest)
+			Expect(err).To(BeNil())
+		})
+
+		It("Should add labels and annotations to the object", func() {
+			obj.MergeMetadata(map[string]string{"team": "platform"},
+				map[string]string{"cert-manager.io/inject-ca-from": "other/ca"})
+
+			labels, _ := ParseFieldPath("metadata.labels.team")
+			Expect(obj.Lookup(labels)).To(Equal([]interface{}{"platform"}))
+
+			annotations, _ := ParseFieldPath(
+				"metadata.annotations['cert-manager.io/inject-ca-from']")
+			Expect(obj.Lookup(annotations)).To(Equal([]interface{}{"default/ca"}))
+		})
+
+		It("Should add labels to the pod templates of workloads", func() {
+			obj.MergePodTemplateMetadata(map[string]string{"team": "platform"}, nil)
+
+			labels, _ := ParseFieldPath("spec.template.metadata.labels.team")
+			Expect(obj.Lookup(labels)).To(Equal([]interface{}{"platform"}))
+		})
+	})
+
+	Describe("DecodeObjects", func() {
+
+		It("Should decode a single object", func() {
+			objects, err := DecodeObjects(`{"kind": "Namespace", "metadata": {"name": "test"}}`)
+			Expect(err).To(BeNil())
+			Expect(len(objects)).To(Equal(1))
+			Expect(objects[0].Name()).To(Equal("test"))
+		})
+
+		It("Should decode the items of a list", func() {
+			objects, err := DecodeObjects(`{"kind": "List", "items": [
+				{"kind": "Namespace", "metadata": {"name": "test", "annotations": {"owner": "a"}}},
+				{"kind": "Namespace", "metadata": {"name": "other"}}]}`)
+			Expect(err).To(BeNil())
+			Expect(len(objects)).To(Equal(2))
+			Expect(objects[0].Annotations()).To(Equal(map[string]string{"owner": "a"}))
+			Expect(objects[1].Annotations()).To(BeEmpty())
+		})
+
+		It("Should decode an empty output", func() {
+			objects, err := DecodeObjects("\n")
+			Expect(err).To(BeNil())
+			Expect(objects).To(BeEmpty())
+		})
+	})
+
+	Describe("EvaluateJSONPath", func() {
+
+		It("Should render scalars, lists and multiple results", func() {
+			obj, err := DecodeObject(`{"status": {"loadBalancer": {"ingress": [
+				{"hostname": "a.example.com"}, {"hostname": "b.example.com"}]}},
+				"spec": {"replicas": 3}}`)
+			Expect(err).To(BeNil())
+
+			value, err := obj.EvaluateJSONPath(
+				"{.status.loadBalancer.ingress[0].hostname}")
+			Expect(err).To(BeNil())
+			Expect(value).To(Equal("a.example.com"))
+
+			value, _ = obj.EvaluateJSONPath(".status.loadBalancer.ingress[*].hostname")
+			Expect(value).To(Equal("a.example.com b.example.com"))
+
+			value, _ = obj.EvaluateJSONPath("spec.replicas")
+			Expect(value).To(Equal("3"))
+
+			value, _ = obj.EvaluateJSONPath("spec")
+			Expect(value).To(Equal(`{"replicas":3}`))
+
+			value, _ = obj.EvaluateJSONPath("spec.missing")
+			Expect(value).To(Equal(""))
+		})
+
+		It("Should filter list elements", func() {
+			obj, err := DecodeObject(`{"kind": "List", "items": [
+				{"kind": "Deployment", "metadata": {"name": "nginx"}},
+				{"kind": "Service", "metadata": {"name": "nginx-svc"}}]}`)
+			Expect(err).To(BeNil())
+
+			value, err := obj.EvaluateJSONPath(
+				`{.items[?(@.kind=="Service")].metadata.name}`)
+			Expect(err).To(BeNil())
+			Expect(value).To(Equal("nginx-svc"))
+
+			value, _ = obj.EvaluateJSONPath(
+				`{.items[?(@.kind!="Service")].metadata.name}`)
+			Expect(value).To(Equal("nginx"))
+		})
+	})
+})
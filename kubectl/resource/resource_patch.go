@@ -0,0 +1,253 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	yamlReader "github.com/kubernetes/apimachinery/pkg/util/yaml"
+)
+
+const (
+	StrategicPatch = "strategic"
+	MergePatch     = "merge"
+	JSONPatch      = "json"
+)
+
+// PatchField is a field modified by a patch.
+type PatchField struct {
+	Path []string
+	// value set by the patch, unless the patch removes the field
+	Value   interface{}
+	Removed bool
+	// false when the value set by the patch cannot be predicted, i.e. for
+	// json patch move and copy operations
+	Checked bool
+}
+
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value"`
+}
+
+// DecodePatch converts a YAML or JSON patch into its JSON form.
+func DecodePatch(patch string) (string, error) {
+	jsonPatch, err := yamlReader.ToJSON([]byte(patch))
+	if err != nil {
+		return "", err
+	}
+	return string(jsonPatch), nil
+}
+
+// ParsePatchFields lists the fields modified by a patch. Lists are handled as
+// a single field by merge and strategic merge patches.
+func ParsePatchFields(patchType, patch string) ([]PatchField, error) {
+	jsonPatch, err := DecodePatch(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	switch patchType {
+	case StrategicPatch, MergePatch:
+		var patchObj map[string]interface{}
+		if err := json.Unmarshal([]byte(jsonPatch), &patchObj); err != nil {
+			return nil, fmt.Errorf("decoding %s patch: %v", patchType, err)
+		}
+		return mergePatchFields(nil, patchObj), nil
+	case JSONPatch:
+		var operations []jsonPatchOperation
+		if err := json.Unmarshal([]byte(jsonPatch), &operations); err != nil {
+			return nil, fmt.Errorf("decoding json patch: %v", err)
+		}
+		return jsonPatchFields(operations)
+	default:
+		return nil, fmt.Errorf("unknown patch type %q", patchType)
+	}
+}
+
+func mergePatchFields(path []string, patch map[string]interface{}) []PatchField {
+	fields := make([]PatchField, 0)
+	for k, v := range patch {
+		fieldPath := append(append([]string{}, path...), k)
+		// strategic merge patch directives are not fields of the object
+		if strings.HasPrefix(k, "$") {
+			continue
+		}
+		switch typed := v.(type) {
+		case map[string]interface{}:
+			fields = append(fields, mergePatchFields(fieldPath, typed)...)
+		case nil:
+			fields = append(fields,
+				PatchField{Path: fieldPath, Removed: true, Checked: true})
+		default:
+			fields = append(fields,
+				PatchField{Path: fieldPath, Value: typed, Checked: true})
+		}
+	}
+	return fields
+}
+
+func jsonPatchFields(operations []jsonPatchOperation) ([]PatchField, error) {
+	fields := make([]PatchField, 0)
+	for _, operation := range operations {
+		path := ParsePointer(operation.Path)
+		// appending to a list does not target a stable field
+		if len(path) > 0 && path[len(path)-1] == "-" {
+			continue
+		}
+		switch operation.Op {
+		case "add", "replace":
+			fields = append(fields,
+				PatchField{Path: path, Value: operation.Value, Checked: true})
+		case "remove":
+			fields = append(fields,
+				PatchField{Path: path, Removed: true, Checked: true})
+		case "move":
+			fields = append(fields,
+				PatchField{Path: ParsePointer(operation.From)},
+				PatchField{Path: path})
+		case "copy":
+			fields = append(fields, PatchField{Path: path})
+		case "test":
+		default:
+			return nil, fmt.Errorf("unknown json patch operation %q",
+				operation.Op)
+		}
+	}
+	return fields, nil
+}
+
+// ParsePointer splits a JSON pointer (RFC 6901) into its segments.
+func ParsePointer(pointer string) []string {
+	segments := make([]string, 0)
+	for _, segment := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		segment = strings.Replace(segment, "~1", "/", -1)
+		segment = strings.Replace(segment, "~0", "~", -1)
+		segments = append(segments, segment)
+	}
+	return segments
+}
+
+// Pointer returns the JSON pointer (RFC 6901) of the field.
+func Pointer(path []string) string {
+	escaped := make([]string, len(path))
+	for i, segment := range path {
+		segment = strings.Replace(segment, "~", "~0", -1)
+		escaped[i] = strings.Replace(segment, "/", "~1", -1)
+	}
+	return "/" + strings.Join(escaped, "/")
+}
+
+// PatchFieldApplied reports whether the live object still holds the value set
+// by the patch. With strategic merge patches, the elements of a patched list
+// only need to be found in the live list.
+func PatchFieldApplied(field PatchField, live Object, strategic bool) bool {
+	if !field.Checked {
+		return true
+	}
+	values := live.Lookup(field.Path)
+	if field.Removed {
+		return len(values) == 0
+	}
+	if len(values) == 0 {
+		return false
+	}
+
+	patchList, isList := field.Value.([]interface{})
+	liveList, liveIsList := values[0].([]interface{})
+	if strategic && isList && liveIsList {
+		for _, patchElem := range patchList {
+			found := false
+			for _, liveElem := range liveList {
+				if IsSubset(patchElem, liveElem) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+	return IsSubset(field.Value, values[0])
+}
+
+// PatchOriginals holds the values of the patched fields before the patch,
+// indexed by JSON pointer.
+type PatchOriginals struct {
+	Values map[string]interface{} `json:"values"`
+	Absent []string               `json:"absent"`
+}
+
+// Capture records the current value of the given fields, unless already
+// captured.
+func (p *PatchOriginals) Capture(fields []PatchField, live Object) {
+	if p.Values == nil {
+		p.Values = make(map[string]interface{})
+	}
+	for _, field := range fields {
+		pointer := Pointer(field.Path)
+		if p.captured(pointer) {
+			continue
+		}
+		values := live.Lookup(field.Path)
+		if len(values) == 0 {
+			p.Absent = append(p.Absent, pointer)
+		} else {
+			p.Values[pointer] = deepCopy(values[0])
+		}
+	}
+}
+
+func (p *PatchOriginals) captured(pointer string) bool {
+	if _, ok := p.Values[pointer]; ok {
+		return true
+	}
+	for _, absent := range p.Absent {
+		if absent == pointer {
+			return true
+		}
+	}
+	return false
+}
+
+// RestorePatch builds the JSON patch restoring the captured values on the
+// live object, or an empty string when there is nothing to restore.
+func (p *PatchOriginals) RestorePatch(live Object) (string, error) {
+	operations := make([]jsonPatchOperation, 0)
+
+	for _, pointer := range p.Absent {
+		if len(live.Lookup(ParsePointer(pointer))) != 0 {
+			operations = append(operations,
+				jsonPatchOperation{Op: "remove", Path: pointer})
+		}
+	}
+	pointers := make([]string, 0, len(p.Values))
+	for pointer := range p.Values {
+		pointers = append(pointers, pointer)
+	}
+	sort.Strings(pointers)
+
+	for _, pointer := range pointers {
+		value := p.Values[pointer]
+		op := "add"
+		if len(live.Lookup(ParsePointer(pointer))) != 0 {
+			op = "replace"
+		}
+		operations = append(operations,
+			jsonPatchOperation{Op: op, Path: pointer, Value: value})
+	}
+
+	if len(operations) == 0 {
+		return "", nil
+	}
+	out, err := json.Marshal(operations)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
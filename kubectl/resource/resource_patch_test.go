@@ -0,0 +1,110 @@
+package resource_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+)
+
+var _ = Describe("ResourcePatch", func() {
+
+	const live = `{
+  "kind": "Deployment",
+  "metadata": {"name": "coredns", "annotations": {"a": "1"}},
+  "spec": {
+    "replicas": 2,
+    "template": {"spec": {"tolerations": [{"key": "existing"}]}}
+  }
+}`
+
+	var liveObj Object
+
+	BeforeEach(func() {
+		var err error
+		liveObj, err = DecodeObject(live)
+		Expect(err).To(BeNil())
+	})
+
+	Describe("ParsePatchFields", func() {
+
+		It("Should list the leaves of a merge patch", func() {
+			fields, err := ParsePatchFields(MergePatch,
+				"metadata:\n  annotations:\n    a: null\nspec:\n  replicas: 3\n")
+			Expect(err).To(BeNil())
+			Expect(len(fields)).To(Equal(2))
+
+			for _, field := range fields {
+				switch Pointer(field.Path) {
+				case "/metadata/annotations/a":
+					Expect(field.Removed).To(BeTrue())
+				case "/spec/replicas":
+					Expect(field.Value).To(BeNumerically("==", 3))
+				default:
+					Fail("unexpected field " + Pointer(field.Path))
+				}
+			}
+		})
+
+		It("Should list the paths of a json patch", func() {
+			fields, err := ParsePatchFields(JSONPatch,
+				`[{"op": "replace", "path": "/metadata/annotations/a~1b", "value": "2"},
+				  {"op": "add", "path": "/spec/template/spec/tolerations/-", "value": {}}]`)
+			Expect(err).To(BeNil())
+			Expect(len(fields)).To(Equal(1))
+			Expect(fields[0].Path).To(Equal(
+				[]string{"metadata", "annotations", "a/b"}))
+		})
+
+		It("Should reject unknown patch types", func() {
+			_, err := ParsePatchFields("unknown", "{}")
+			Expect(err).NotTo(BeNil())
+		})
+	})
+
+	Describe("PatchFieldApplied", func() {
+
+		It("Should find patched list elements with strategic merge", func() {
+			fields, _ := ParsePatchFields(StrategicPatch,
+				`{"spec": {"template": {"spec": {"tolerations": [{"key": "existing"}]}}}}`)
+			Expect(PatchFieldApplied(fields[0], liveObj, true)).To(BeTrue())
+			Expect(PatchFieldApplied(fields[0], liveObj, false)).To(BeTrue())
+
+			fields, _ = ParsePatchFields(StrategicPatch,
+				`{"spec": {"template": {"spec": {"tolerations": [{"key": "new"}]}}}}`)
+			Expect(PatchFieldApplied(fields[0], liveObj, true)).To(BeFalse())
+		})
+
+		It("Should detect changed values", func() {
+			fields, _ := ParsePatchFields(MergePatch, `{"spec": {"replicas": 3}}`)
+			Expect(PatchFieldApplied(fields[0], liveObj, false)).To(BeFalse())
+
+			fields, _ = ParsePatchFields(MergePatch, `{"spec": {"replicas": 2}}`)
+			Expect(PatchFieldApplied(fields[0], liveObj, false)).To(BeTrue())
+		})
+	})
+
+	Describe("PatchOriginals", func() {
+
+		It("Should restore the captured values", func() {
+			fields, _ := ParsePatchFields(MergePatch,
+				`{"metadata": {"annotations": {"b": "2"}}, "spec": {"replicas": 3}}`)
+			originals := &PatchOriginals{}
+			originals.Capture(fields, liveObj)
+
+			patched, _ := DecodeObject(`{"metadata": {"annotations": {"a": "1", "b": "2"}},
+				"spec": {"replicas": 3}}`)
+			restorePatch, err := originals.RestorePatch(patched)
+			Expect(err).To(BeNil())
+
+			var operations []map[string]interface{}
+			Expect(json.Unmarshal([]byte(restorePatch), &operations)).To(BeNil())
+			Expect(operations).To(Equal([]map[string]interface{}{
+				{"op": "remove", "path": "/metadata/annotations/b", "value": nil},
+				{"op": "replace", "path": "/spec/replicas", "value": float64(2)},
+			}))
+		})
+	})
+})
@@ -0,0 +1,316 @@
+package kubectl
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func resourceConfigMapFromFiles() *schema.Resource {
+	return resourceFromFiles("ConfigMap", nil)
+}
+
+func resourceSecretFromFiles() *schema.Resource {
+	return resourceFromFiles("Secret", map[string]*schema.Schema{
+		"type": &schema.Schema{
+			Type:     schema.TypeString,
+			Optional: true,
+			Default:  "Opaque",
+			ForceNew: true,
+		},
+	})
+}
+
+// Builds the resources generating a ConfigMap or a Secret from files. Only
+// the hash of the data is kept in the state, the data itself being read from
+// the files on every plan.
+func resourceFromFiles(kind string,
+	extraSchema map[string]*schema.Schema) *schema.Resource {
+
+	resourceSchema := map[string]*schema.Schema{
+		"name": &schema.Schema{
+			Type:     schema.TypeString,
+			Required: true,
+			ForceNew: true,
+		},
+		"namespace": &schema.Schema{
+			Type:     schema.TypeString,
+			Optional: true,
+			ForceNew: true,
+		},
+		// files, directories or glob patterns, each file being stored under
+		// its base name
+		"files": &schema.Schema{
+			Type:     schema.TypeList,
+			Required: true,
+			MinItems: 1,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		},
+		"hash_suffix": &schema.Schema{
+			Type:     schema.TypeBool,
+			Optional: true,
+			Default:  false,
+		},
+		"labels": &schema.Schema{
+			Type:     schema.TypeMap,
+			Optional: true,
+		},
+		"annotations": &schema.Schema{
+			Type:     schema.TypeMap,
+			Optional: true,
+		},
+		// name of the object, suffixed with the hash of its data when
+		// hash_suffix is set
+		"generated_name": &schema.Schema{
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+		"content_hash": &schema.Schema{
+			Type:     schema.TypeString,
+			Computed: true,
+		},
+	}
+	for key, value := range extraSchema {
+		resourceSchema[key] = value
+	}
+
+	return &schema.Resource{
+		Create: func(d *schema.ResourceData, m interface{}) error {
+			if err := applyFromFiles(kind, d, m); err != nil {
+				return err
+			}
+			d.SetId(d.Get("namespace").(string) + "/" +
+				strings.ToLower(kind) + "/" + d.Get("name").(string))
+			return nil
+		},
+		Read: func(d *schema.ResourceData, m interface{}) error {
+			return readFromFiles(kind, d, m)
+		},
+		Update: func(d *schema.ResourceData, m interface{}) error {
+			return applyFromFiles(kind, d, m)
+		},
+		Delete: func(d *schema.ResourceData, m interface{}) error {
+			return deleteFromFiles(kind, d, m)
+		},
+
+		CustomizeDiff: resourceFromFilesCustomizeDiff,
+
+		Schema: resourceSchema,
+	}
+}
+
+// Hashes the files on every plan, so that changes to their content trigger
+// an update
+func resourceFromFilesCustomizeDiff(d *schema.ResourceDiff,
+	m interface{}) error {
+
+	if !d.NewValueKnown("files") {
+		return d.SetNewComputed("content_hash")
+	}
+	data, err := readDataFiles(expandStringList(d.Get("files").([]interface{})))
+	if err != nil {
+		return err
+	}
+	contentHash := resource.HashData(data)
+	if contentHash == d.Get("content_hash").(string) &&
+		!d.HasChange("hash_suffix") {
+		return nil
+	}
+	if err := d.SetNew("content_hash", contentHash); err != nil {
+		return err
+	}
+	return d.SetNew("generated_name", generatedName(
+		d.Get("name").(string), contentHash, d.Get("hash_suffix").(bool)))
+}
+
+func generatedName(name, contentHash string, hashSuffix bool) string {
+	if !hashSuffix {
+		return name
+	}
+	return name + "-" + contentHash[:10]
+}
+
+// Reads the files matching the patterns, indexed by their base name
+func readDataFiles(patterns []string) (map[string][]byte, error) {
+	data := make(map[string][]byte)
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file pattern %q: %s", pattern, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no file matches %q", pattern)
+		}
+
+		files := make([]string, 0, len(matches))
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if !info.IsDir() {
+				files = append(files, match)
+				continue
+			}
+			// the regular files of directories are read, without recursing
+			entries, err := ioutil.ReadDir(match)
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range entries {
+				if entry.Mode().IsRegular() {
+					files = append(files, filepath.Join(match, entry.Name()))
+				}
+			}
+		}
+
+		for _, file := range files {
+			key := filepath.Base(file)
+			if _, ok := data[key]; ok {
+				return nil, fmt.Errorf("duplicate key %q from file %s",
+					key, file)
+			}
+			content, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			data[key] = content
+		}
+	}
+	return data, nil
+}
+
+func applyFromFiles(kind string, d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+
+	kubectlCLIConfig, err := NewKubectlConfig(config)
+	if err != nil {
+		return fmt.Errorf("error while processing kubeconfig file: %s", err)
+	}
+	defer kubectlCLIConfig.Cleanup()
+
+	data, err := readDataFiles(expandStringList(d.Get("files").([]interface{})))
+	if err != nil {
+		return err
+	}
+	contentHash := resource.HashData(data)
+	name := generatedName(d.Get("name").(string), contentHash,
+		d.Get("hash_suffix").(bool))
+	namespace := d.Get("namespace").(string)
+
+	obj := resource.NewDataObject(kind, name, namespace, data)
+	if kind == "Secret" {
+		obj["type"] = d.Get("type").(string)
+	}
+	obj.MergeMetadata(
+		expandStringMap(d.Get("labels").(map[string]interface{})),
+		expandStringMap(d.Get("annotations").(map[string]interface{})))
+	manifest, err := obj.Encode()
+	if err != nil {
+		return err
+	}
+
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	applyCommand := commandFactory.CreateApplyManifestCommand(manifest, namespace)
+	if err := applyCommand.RunCommand(); err != nil {
+		return fmt.Errorf("error while applying %s %s: %s", kind, name, err)
+	}
+
+	// objects with hash suffixes are replaced rather than updated, the
+	// previous one being deleted once its replacement exists. The planned
+	// generated_name is already the new one.
+	previous, _ := d.GetChange("generated_name")
+	previousName := previous.(string)
+	if d.Id() != "" && previousName != "" && previousName != name {
+		deleteCommand := commandFactory.CreateDeleteByHandleCommand(
+			strings.ToLower(kind)+"/"+previousName, namespace)
+		if err := deleteCommand.RunCommand(); err != nil {
+			return err
+		}
+		if config.DryRun != "" {
+			// the previous object still exists, it is kept in the state to
+			// be replaced once applied for real
+			previousHash, _ := d.GetChange("content_hash")
+			if err := d.Set("generated_name", previousName); err != nil {
+				return err
+			}
+			return d.Set("content_hash", previousHash)
+		}
+	}
+
+	if err := d.Set("generated_name", name); err != nil {
+		return err
+	}
+	return d.Set("content_hash", contentHash)
+}
+
+// Checks that the data of the live object still matches the files, forcing
+// an update otherwise
+func readFromFiles(kind string, d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+
+	kubectlCLIConfig, err := NewKubectlConfig(config)
+	if err != nil {
+		return fmt.Errorf("error while processing kubeconfig file: %s", err)
+	}
+	defer kubectlCLIConfig.Cleanup()
+
+	stdout := &bytes.Buffer{}
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	getCommand := commandFactory.CreateGetJSONByHandleCommand(
+		strings.ToLower(kind)+"/"+d.Get("generated_name").(string),
+		d.Get("namespace").(string), stdout)
+
+	if err := getCommand.RunCommand(); err != nil {
+		return err
+	}
+	liveObjects, err := resource.DecodeObjects(stdout.String())
+	if err != nil {
+		return fmt.Errorf("decoding response: %v", err)
+	}
+	if len(liveObjects) == 0 {
+		log.Printf("[DEBUG] %s %s not found", kind, d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	data, err := liveObjects[0].Data()
+	if err != nil {
+		return err
+	}
+	if resource.HashData(data) != d.Get("content_hash").(string) {
+		log.Printf("[INFO] data of %s %s drifted from its files", kind, d.Id())
+		d.Set("content_hash", "")
+	}
+	return nil
+}
+
+func deleteFromFiles(kind string, d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+
+	kubectlCLIConfig, err := NewKubectlConfig(config)
+	if err != nil {
+		return fmt.Errorf("error while processing kubeconfig file: %s", err)
+	}
+	defer kubectlCLIConfig.Cleanup()
+
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	deleteCommand := commandFactory.CreateDeleteByHandleCommand(
+		strings.ToLower(kind)+"/"+d.Get("generated_name").(string),
+		d.Get("namespace").(string))
+	if err := deleteCommand.RunCommand(); err != nil {
+		return err
+	}
+	if config.DryRun != "" {
+		return dryRunDestroyError(config.DryRun,
+			kind+" "+d.Get("generated_name").(string)+" was not deleted")
+	}
+	return nil
+}
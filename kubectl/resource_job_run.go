@@ -0,0 +1,273 @@
+package kubectl
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+const (
+	// interval between two checks of the status of a running Job
+	jobPollInterval = 5 * time.Second
+
+	jobResourceType = "job.v1.batch"
+)
+
+func resourceJobRun() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceJobRunCreate,
+		// the run is not repeated when the Job is deleted by its TTL
+		Read:   schema.Noop,
+		Update: schema.Noop,
+		Delete: resourceJobRunDelete,
+
+		Schema: map[string]*schema.Schema{
+			// manifest of the Job, named after its name or generateName
+			"template": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"namespace": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				Computed: true,
+				ForceNew: true,
+			},
+			// arbitrary values, such as image tags, running a new Job when
+			// they change
+			"triggers": &schema.Schema{
+				Type:     schema.TypeMap,
+				Optional: true,
+				ForceNew: true,
+			},
+			// -1 keeps the Job once finished
+			"ttl_seconds_after_finished": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+				Default:  -1,
+				ForceNew: true,
+			},
+			"delete_on_destroy": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  true,
+			},
+			"timeout": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "10m",
+				ValidateFunc: validateDuration,
+			},
+			"log_lines": &schema.Schema{
+				Type:     schema.TypeInt,
+				Optional: true,
+				Default:  20,
+			},
+			"job_name": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			// succeeded or failed
+			"status": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			// exit code of the latest pod of the Job
+			"exit_code": &schema.Schema{
+				Type:     schema.TypeInt,
+				Computed: true,
+			},
+			// tail of the logs of the latest pod of the Job
+			"logs": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+// The steps involved in running a Job are:
+//  1. creating a uniquely named Job from the template
+//  2. waiting for the Job to succeed or fail
+//  3. fetching the exit code and the tail of the logs of its latest pod
+//
+// A failed Job fails the apply, the resource being tainted so that a new Job
+// runs on the next apply.
+func resourceJobRunCreate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+
+	kubectlCLIConfig, err := NewKubectlConfig(config)
+	if err != nil {
+		return fmt.Errorf("error while processing kubeconfig file: %s", err)
+	}
+	defer kubectlCLIConfig.Cleanup()
+
+	template, err := resource.DecodeObject(d.Get("template").(string))
+	if err != nil {
+		return fmt.Errorf("decoding template: %v", err)
+	}
+	job, err := resource.NewJobRun(template, randomID(4),
+		d.Get("ttl_seconds_after_finished").(int))
+	if err != nil {
+		return err
+	}
+	manifest, err := job.Encode()
+	if err != nil {
+		return err
+	}
+	timeout, err := time.ParseDuration(d.Get("timeout").(string))
+	if err != nil {
+		return err
+	}
+	namespace := d.Get("namespace").(string)
+	switch {
+	case job.Namespace() == "":
+	case namespace == "":
+		namespace = job.Namespace()
+	case job.Namespace() != namespace:
+		return fmt.Errorf("Job %q sets namespace %q which conflicts with the "+
+			"resource namespace %q", job.Name(), job.Namespace(), namespace)
+	}
+
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	applyCommand := commandFactory.CreateApplyManifestCommand(manifest,
+		namespace)
+	if err := applyCommand.RunCommand(); err != nil {
+		return err
+	}
+	if config.DryRun != "" {
+		// no Job ran: the resource is left out of the state, so that the
+		// Job runs on the next apply
+		log.Printf("[INFO] dry-run: not waiting for job %s", job.Name())
+		return nil
+	}
+	d.SetId(namespace + "/" + job.Name())
+	d.Set("namespace", namespace)
+	d.Set("job_name", job.Name())
+
+	handle := jobResourceType + "/" + job.Name()
+	status, message, err := waitForJob(handle, namespace, timeout,
+		kubectlCLIConfig)
+	if err != nil {
+		return err
+	}
+	exitCode, logs, err := jobPodResult(job.Name(), namespace,
+		d.Get("log_lines").(int), kubectlCLIConfig)
+	if err != nil {
+		return err
+	}
+
+	d.Set("status", status)
+	d.Set("exit_code", exitCode)
+	d.Set("logs", logs)
+	if status == resource.JobFailed {
+		return fmt.Errorf("job %s failed: %s\n%s", job.Name(), message, logs)
+	}
+	return nil
+}
+
+// Polls the Job until it succeeds or fails, or the timeout expires
+func waitForJob(handle, namespace string, timeout time.Duration,
+	kubectlCLIConfig *KubectlConfig) (status, message string, err error) {
+
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	deadline := time.Now().Add(timeout)
+	for {
+		stdout := &bytes.Buffer{}
+		getCommand := commandFactory.CreateGetJSONByHandleCommand(handle,
+			namespace, stdout)
+		if err := getCommand.RunCommand(); err != nil {
+			return "", "", err
+		}
+		if strings.TrimSpace(stdout.String()) == "" {
+			return "", "", fmt.Errorf("%s not found in namespace %q", handle,
+				namespace)
+		}
+		job, err := resource.DecodeObject(stdout.String())
+		if err != nil {
+			return "", "", fmt.Errorf("decoding response: %v", err)
+		}
+
+		status, message := job.JobStatus()
+		if status != resource.JobRunning {
+			return status, message, nil
+		}
+		if time.Now().After(deadline) {
+			return "", "", fmt.Errorf("timeout while waiting for %s to finish",
+				handle)
+		}
+		log.Printf("[DEBUG] waiting for %s to finish", handle)
+		time.Sleep(jobPollInterval)
+	}
+}
+
+// Fetches the exit code and the tail of the logs of the latest pod of a Job.
+// The exit code is -1 when no container of the pod terminated.
+func jobPodResult(jobName, namespace string, logLines int,
+	kubectlCLIConfig *KubectlConfig) (exitCode int, logs string, err error) {
+
+	stdout := &bytes.Buffer{}
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	getCommand := commandFactory.CreateGetJSONBySelectorCommand("pods",
+		namespace, "job-name="+jobName, "", stdout)
+	if err := getCommand.RunCommand(); err != nil {
+		return 0, "", err
+	}
+	pods, err := resource.DecodeObjects(stdout.String())
+	if err != nil {
+		return 0, "", fmt.Errorf("decoding response: %v", err)
+	}
+	pod := resource.LatestPod(pods)
+	if pod == nil {
+		return -1, "", nil
+	}
+	exitCode, terminated := pod.PodExitCode()
+	if !terminated {
+		exitCode = -1
+	}
+
+	logsOutput := &bytes.Buffer{}
+	logsCommand := commandFactory.CreateLogsCommand("pod/"+pod.Name(),
+		namespace, logLines, logsOutput)
+	if err := logsCommand.RunCommand(); err != nil {
+		// the logs are informative only
+		log.Printf("[WARN] could not fetch the logs of pod %s: %s",
+			pod.Name(), err)
+	}
+	return exitCode, kubectlCLIConfig.Redactor.Redact(logsOutput.String()), nil
+}
+
+// Deletes the Job and its pods, unless they were already deleted by the TTL
+// controller or delete_on_destroy is unset
+func resourceJobRunDelete(d *schema.ResourceData, m interface{}) error {
+	if !d.Get("delete_on_destroy").(bool) {
+		return nil
+	}
+
+	config := m.(*Config)
+
+	kubectlCLIConfig, err := NewKubectlConfig(config)
+	if err != nil {
+		return fmt.Errorf("error while processing kubeconfig file: %s", err)
+	}
+	defer kubectlCLIConfig.Cleanup()
+
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	deleteCommand := commandFactory.CreateDeleteByHandleCommand(
+		jobResourceType+"/"+d.Get("job_name").(string),
+		d.Get("namespace").(string))
+	if err := deleteCommand.RunCommand(); err != nil {
+		return err
+	}
+	if config.DryRun != "" {
+		return dryRunDestroyError(config.DryRun,
+			"job "+d.Get("job_name").(string)+" was not deleted")
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package kubectl
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Job runs", func() {
+
+	const template = `
+apiVersion: batch/v1
+kind: Job
+metadata:
+  generateName: migrate-
+spec:
+  template:
+    spec:
+      restartPolicy: Never
+      containers:
+      - name: migrate
+        image: app:1.2.0
+`
+
+	var kubectl *fakeKubectl
+
+	BeforeEach(func() {
+		kubectl = installFakeKubectl()
+	})
+
+	AfterEach(func() {
+		kubectl.restore()
+	})
+
+	It("Should not record dry runs", func() {
+		state, err := applyResource(resourceJobRun(), nil,
+			map[string]interface{}{"template": template},
+			&Config{DryRun: DryRunServer})
+		Expect(err).To(BeNil())
+		Expect(state == nil || state.ID == "").To(BeTrue())
+		Expect(kubectl.commands()).To(Equal([]string{
+			"apply -f - --dry-run=server -o json"}))
+	})
+
+	It("Should reject a template namespace conflicting with the resource", func() {
+		conflicting := strings.Replace(template, "generateName: migrate-",
+			"generateName: migrate-\n  namespace: staging", 1)
+
+		_, err := applyResource(resourceJobRun(), nil,
+			map[string]interface{}{"template": conflicting,
+				"namespace": "production"}, &Config{})
+		Expect(err).NotTo(BeNil())
+		Expect(err.Error()).To(ContainSubstring("conflicts"))
+		Expect(kubectl.commands()).To(BeEmpty())
+	})
+})
@@ -4,12 +4,12 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
 	"github.com/hashicorp/terraform/helper/schema"
@@ -23,16 +23,34 @@ func resourceManifest() *schema.Resource {
 		Update: resourceManifestUpdate,
 		Delete: resourceManifestDelete,
 
+		CustomizeDiff: resourceManifestCustomizeDiff,
+
+		Timeouts: &schema.ResourceTimeout{
+			Create: schema.DefaultTimeout(10 * time.Minute),
+			Update: schema.DefaultTimeout(10 * time.Minute),
+		},
+
 		Schema: map[string]*schema.Schema{
 			"content": &schema.Schema{
-				Type:      schema.TypeString,
-				Required:  true,
-				Sensitive: true,
+				Type:          schema.TypeString,
+				Optional:      true,
+				Sensitive:     true,
+				ConflictsWith: []string{"kustomize_path"},
 				StateFunc: func(val interface{}) string {
 					contentHash := sha256.Sum256([]byte(val.(string)))
 					return fmt.Sprintf("%x", contentHash)
 				},
 			},
+			"kustomize_path": &schema.Schema{
+				Type:          schema.TypeString,
+				Optional:      true,
+				ConflictsWith: []string{"content"},
+			},
+			// hash of the build inputs of the kustomization
+			"kustomize_hash": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
 			"name": &schema.Schema{
 				Type:     schema.TypeString,
 				Required: true,
@@ -42,6 +60,115 @@ func resourceManifest() *schema.Resource {
 				Optional: true,
 				ForceNew: true,
 			},
+			"override_namespace": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"labels": &schema.Schema{
+				Type:     schema.TypeMap,
+				Optional: true,
+			},
+			"annotations": &schema.Schema{
+				Type:     schema.TypeMap,
+				Optional: true,
+			},
+			"label_pod_templates": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"convert_deprecated_apis": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"takeover": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"owner_id": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+			"prune": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			// restricts pruning to the listed `<group>/<version>/<kind>`
+			// among the types of the previous and current documents
+			"prune_allowlist": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			// stores the hash of the Secret values in the state rather than
+			// the values themselves
+			"redact_secrets": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			// delete or orphan
+			"on_destroy": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      OnDestroyDelete,
+				ValidateFunc: validateOnDestroy,
+			},
+			"deletion_protection": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			// deletes CRDs even when custom resources not belonging to the
+			// manifest exist
+			"allow_crd_data_loss": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"rollback_on_failure": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			// deletes and recreates the objects whose changes are rejected
+			// as immutable
+			"force_replace_on_immutable": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			// objects replaced by the last apply, or to be replaced by the
+			// planned one
+			"replaced_objects": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			// name -> jsonpath expression evaluated against the list of the
+			// applied objects
+			"outputs": &schema.Schema{
+				Type:     schema.TypeMap,
+				Optional: true,
+			},
+			"wait_for_outputs": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"output_values": &schema.Schema{
+				Type:     schema.TypeMap,
+				Computed: true,
+			},
+			"ignore_fields": &schema.Schema{
+				Type:     schema.TypeList,
+				Optional: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
 			"resources": {
 				Type:     schema.TypeSet,
 				Computed: true,
@@ -67,6 +194,82 @@ func resourceManifest() *schema.Resource {
 	}
 }
 
+// Hashes the build inputs of the kustomization, so that changes to the
+// kustomization trigger an update, and marks the output values as unknown
+// until the objects get applied again
+func resourceManifestCustomizeDiff(d *schema.ResourceDiff, m interface{}) error {
+	if path := d.Get("kustomize_path").(string); path != "" {
+		kustomizeHash, err := hashKustomization(path)
+		if err != nil {
+			return fmt.Errorf("error while hashing kustomization %s: %s",
+				path, err)
+		}
+		if kustomizeHash != d.Get("kustomize_hash").(string) {
+			if err := d.SetNew("kustomize_hash", kustomizeHash); err != nil {
+				return err
+			}
+		}
+	}
+
+	if d.Id() != "" && d.Get("force_replace_on_immutable").(bool) {
+		if err := planReplacements(d, m.(*Config)); err != nil {
+			return err
+		}
+	}
+
+	if d.Id() == "" || len(d.Get("outputs").(map[string]interface{})) == 0 {
+		return nil
+	}
+	for _, attribute := range []string{"content", "kustomize_path",
+		"kustomize_hash", "outputs", "labels", "annotations",
+		"override_namespace", "label_pod_templates",
+		"convert_deprecated_apis", "redact_secrets"} {
+		if d.HasChange(attribute) {
+			return d.SetNewComputed("output_values")
+		}
+	}
+	return nil
+}
+
+// Shows the objects whose changes would be rejected as immutable as replaced
+// in the plan, by dry-running the apply of the changed manifest
+func planReplacements(d *schema.ResourceDiff, config *Config) error {
+	changed := false
+	for _, attribute := range []string{"content", "kustomize_hash",
+		"convert_deprecated_apis"} {
+		if d.HasChange(attribute) && !d.NewValueKnown(attribute) {
+			// the manifest is only known once applying
+			return nil
+		}
+		changed = changed || d.HasChange(attribute)
+	}
+	if !changed {
+		return nil
+	}
+
+	kubectlCLIConfig, err := NewKubectlConfig(config)
+	if err != nil {
+		return fmt.Errorf("error while processing kubeconfig file: %s", err)
+	}
+	defer kubectlCLIConfig.Cleanup()
+
+	manifestResources, err := manifestDocuments(d, kubectlCLIConfig)
+	if err != nil {
+		return err
+	}
+	manifestResources, err = prepareManifestResources(d, config,
+		stateManifestOwner(d), manifestResources, kubectlCLIConfig)
+	if err != nil {
+		return err
+	}
+	replaced := immutableObjects(manifestResources,
+		d.Get("namespace").(string), kubectlCLIConfig)
+	if len(replaced) == 0 && len(d.Get("replaced_objects").([]interface{})) == 0 {
+		return nil
+	}
+	return d.SetNew("replaced_objects", replaced)
+}
+
 func HashResource(v interface{}) int {
 
 	resource := v.(map[string]interface{})
@@ -98,23 +301,62 @@ func resourceManifestCreate(d *schema.ResourceData, m interface{}) error {
 	if nm, ok := d.GetOk("namespace"); ok {
 		namespace = nm.(string)
 	}
-	manifestResources, err := resource.SplitYAMLDocument(
-		d.Get("content").(string))
+	manifestResources, err := manifestDocuments(d, kubectlCLIConfig)
+	if err != nil {
+		return err
+	}
+	owner, err := newManifestOwner(d)
 	if err != nil {
 		return err
 	}
-	tfResources, err := updateResources(
-		manifestResources, namespace, kubectlCLIConfig)
+	manifestResources, err = prepareManifestResources(
+		d, config, owner, manifestResources, kubectlCLIConfig)
 	if err != nil {
 		return err
 	}
+	audit := newManifestAudit(config, d.Get("name").(string))
+	tfResources, applied, err := updateResources(manifestResources,
+		namespace, owner, audit, config.Protected,
+		d.Get("allow_crd_data_loss").(bool), d.Get("redact_secrets").(bool),
+		d.Get("force_replace_on_immutable").(bool), kubectlCLIConfig)
+	if err != nil {
+		if d.Get("rollback_on_failure").(bool) {
+			rollbackErr := rollbackResources(applied,
+				schema.NewSet(HashResource, []interface{}{}), audit,
+				config.Protected, kubectlCLIConfig)
+			if rollbackErr == nil {
+				return fmt.Errorf("%s (applied objects rolled back)", err)
+			}
+			err = fmt.Errorf("%s (rollback failed: %s)", err, rollbackErr)
+		}
+		if tfResources.Len() == 0 {
+			return err
+		}
+		// keeps track of the objects applied so far: the resource gets
+		// tainted and replaced on the next apply
+		d.Set("resources", tfResources)
+		d.Set("namespace", namespace)
+		d.SetId(d.Get("name").(string))
+		return err
+	}
 	err = d.Set("resources", tfResources)
 	if err != nil {
 		return err
 	}
 	err = d.Set("namespace", namespace)
 	d.SetId(d.Get("name").(string))
-	return nil
+	if err := d.Set("replaced_objects", replacedObjects(applied)); err != nil {
+		return err
+	}
+
+	outputValues, err := manifestOutputs(applied,
+		d.Get("outputs").(map[string]interface{}),
+		d.Get("wait_for_outputs").(bool), d.Timeout(schema.TimeoutCreate),
+		kubectlCLIConfig)
+	if err != nil {
+		return err
+	}
+	return d.Set("output_values", outputValues)
 }
 
 // The steps involved in updating resources are:
@@ -125,6 +367,8 @@ func resourceManifestCreate(d *schema.ResourceData, m interface{}) error {
 //		  with its uid
 //  3. Deletes the resources which where present in the old state but are not
 //     anymore
+//  4. If prune is set, deletes the objects labelled as belonging to the
+//     manifest which are not part of it anymore
 //
 func resourceManifestUpdate(d *schema.ResourceData, m interface{}) error {
 
@@ -136,48 +380,159 @@ func resourceManifestUpdate(d *schema.ResourceData, m interface{}) error {
 	}
 	defer kubectlCLIConfig.Cleanup()
 
-	if d.HasChange("content") {
+	if hasManifestChange(d) {
 
 		var namespace string
 
+		// only the attributes explicitly marked as partial are saved when the
+		// update fails, leaving the content to be applied again
+		d.Partial(true)
+
 		if nm, ok := d.GetOk("namespace"); ok {
 			namespace = nm.(string)
 		}
 		tfOldResources := d.Get("resources").(*schema.Set)
 
-		manifestResources, err := resource.SplitYAMLDocument(
-			d.Get("content").(string))
+		manifestResources, err := manifestDocuments(d, kubectlCLIConfig)
+		if err != nil {
+			return err
+		}
+		owner, err := newManifestOwner(d)
+		if err != nil {
+			return err
+		}
+		manifestResources, err = prepareManifestResources(
+			d, config, owner, manifestResources, kubectlCLIConfig)
 		if err != nil {
 			return err
 		}
-		tfResources, err := updateResources(
-			manifestResources, namespace, kubectlCLIConfig)
+		d.SetPartial("owner_id")
+
+		audit := newManifestAudit(config, d.Get("name").(string))
+		tfResources, applied, err := updateResources(manifestResources,
+			namespace, owner, audit, config.Protected,
+			d.Get("allow_crd_data_loss").(bool),
+			d.Get("redact_secrets").(bool),
+			d.Get("force_replace_on_immutable").(bool), kubectlCLIConfig)
 		if err != nil {
+			if d.Get("rollback_on_failure").(bool) {
+				rollbackErr := rollbackResources(applied, tfOldResources,
+					audit, config.Protected, kubectlCLIConfig)
+				if rollbackErr == nil {
+					return fmt.Errorf("%s (applied objects rolled back)", err)
+				}
+				err = fmt.Errorf("%s (rollback failed: %s)", err, rollbackErr)
+			}
+			d.Set("resources", setUnion(tfResources,
+				setObjectDifference(tfOldResources, tfResources)))
+			d.SetPartial("resources")
 			return err
 		}
 
-		toDelete := setDifference(tfOldResources, tfResources)
-		err = deleteResources(toDelete, kubectlCLIConfig)
+		toDelete := setObjectDifference(tfOldResources, tfResources)
+		err = deleteResources(toDelete, owner, audit, config.Protected,
+			d.Get("allow_crd_data_loss").(bool), kubectlCLIConfig)
 		if err != nil {
+			d.Set("resources", setUnion(tfResources,
+				setObjectDifference(tfOldResources, tfResources)))
+			d.SetPartial("resources")
 			return err
 		}
+		if config.DryRun != "" {
+			// the objects removed from the manifest still exist, they are
+			// kept in the state to be deleted once applied for real
+			tfResources = setUnion(tfResources, toDelete)
+		}
 
+		// the applied objects are recorded before pruning, so that they
+		// are not lost when the prune fails
 		err = d.Set("resources", tfResources)
 		if err != nil {
 			return err
 		}
+		d.SetPartial("resources")
+
+		if d.Get("prune").(bool) {
+			gvks, err := pruneGroupVersionKinds(expandStringList(
+				d.Get("prune_allowlist").([]interface{})),
+				tfOldResources, tfResources)
+			if err != nil {
+				return err
+			}
+			err = pruneResources(owner, tfResources, gvks, audit,
+				config.Protected, d.Get("allow_crd_data_loss").(bool),
+				kubectlCLIConfig)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = d.Set("replaced_objects", replacedObjects(applied))
+		if err != nil {
+			return err
+		}
+
+		outputValues, err := manifestOutputs(applied,
+			d.Get("outputs").(map[string]interface{}),
+			d.Get("wait_for_outputs").(bool), d.Timeout(schema.TimeoutUpdate),
+			kubectlCLIConfig)
+		if err != nil {
+			return err
+		}
+		err = d.Set("output_values", outputValues)
+		if err != nil {
+			return err
+		}
 
+		d.Partial(false)
 	}
 	return nil
 }
 
+// Checks whether any of the attributes used to render the applied documents
+// has changed
+func hasManifestChange(d *schema.ResourceData) bool {
+	manifestAttributes := []string{"content", "kustomize_path",
+		"kustomize_hash", "override_namespace", "labels", "annotations",
+		"label_pod_templates", "outputs", "wait_for_outputs",
+		"convert_deprecated_apis", "redact_secrets"}
+
+	for _, attribute := range manifestAttributes {
+		if d.HasChange(attribute) {
+			return true
+		}
+	}
+	return false
+}
+
 // Simply deletes all the resources in the manifest one by one
 //	1. gets the resources from the terraform state
 //
 //	for each of the retrieved resources:
-//	- delete the resource
+//	- delete the resource, unless it is protected by the provider
+//
+// The resource cannot be destroyed while deletion_protection is set, and its
+// objects are left in the cluster when on_destroy is orphan.
 func resourceManifestDelete(d *schema.ResourceData, m interface{}) error {
+	if d.Get("deletion_protection").(bool) {
+		return fmt.Errorf("kubectl_manifest %s has deletion_protection set: "+
+			"unset it and apply before destroying it", d.Get("name").(string))
+	}
+
 	config := m.(*Config)
+	audit := newManifestAudit(config, d.Get("name").(string))
+	toDelete := d.Get("resources").(*schema.Set)
+
+	if d.Get("on_destroy").(string) == OnDestroyOrphan {
+		for _, tfResource := range toDelete.List() {
+			tfResource := tfResource.(map[string]interface{})
+			log.Printf("[WARN] orphaning %s", tfResource["selflink"])
+			uid, _ := tfResource["uid"].(string)
+			audit.record("orphan", auditStateObject(tfResource), "", uid,
+				time.Now(), nil)
+		}
+		return nil
+	}
 
 	kubectlCLIConfig, err := NewKubectlConfig(config)
 	if err != nil {
@@ -185,8 +540,13 @@ func resourceManifestDelete(d *schema.ResourceData, m interface{}) error {
 	}
 	defer kubectlCLIConfig.Cleanup()
 
-	toDelete := d.Get("resources").(*schema.Set)
-	err = deleteResources(toDelete, kubectlCLIConfig)
+	err = deleteResources(toDelete, stateManifestOwner(d), audit,
+		config.Protected, d.Get("allow_crd_data_loss").(bool),
+		kubectlCLIConfig)
+	if err == nil && config.DryRun != "" {
+		return dryRunDestroyError(config.DryRun, fmt.Sprintf(
+			"the objects of kubectl_manifest %s were not deleted", d.Get("name").(string)))
+	}
 	return err
 }
 
@@ -208,7 +568,8 @@ func resourceManifestRead(d *schema.ResourceData, m interface{}) error {
 
 	log.Printf("[DEBUG] start refreshing object %s", d.Get("name").(string))
 
-	commonResources, errs := getTfResourcesFromK8s(kubectlCLIConfig, d)
+	commonResources, driftedResources, errs := getTfResourcesFromK8s(
+		config, kubectlCLIConfig, d)
 
 	// TODO: make sure to differentiate the kind of errors and abort only when they
 	//       mean that the resource does not exist
@@ -226,15 +587,29 @@ func resourceManifestRead(d *schema.ResourceData, m interface{}) error {
 	if commonResources.Len() < 1 {
 		d.SetId("")
 	}
+	if len(driftedResources) != 0 {
+		for _, selflink := range driftedResources {
+			log.Printf("[INFO] resource %s drifted from its manifest", selflink)
+		}
+		// forces a diff on the content so that the manifest gets re-applied
+		if d.Get("kustomize_path").(string) != "" {
+			d.Set("kustomize_hash", "")
+		} else {
+			d.Set("content", "")
+		}
+	}
 	log.Printf("[DEBUG] done refreshing object %s", d.Get("name").(string))
 
 	return nil
 }
 
-func getTfResourcesFromK8s(kubectlCLIConfig *KubectlConfig, d *schema.ResourceData) (
-	*schema.Set, []error) {
+func getTfResourcesFromK8s(config *Config, kubectlCLIConfig *KubectlConfig,
+	d *schema.ResourceData) (*schema.Set, []string, []error) {
 
 	errs := make([]error, 0)
+	drifted := make([]string, 0)
+	options := newDriftOptions(config, d)
+	audit := newManifestAudit(config, d.Get("name").(string))
 
 	tfResources := d.Get("resources").(*schema.Set)
 	tfResourcesList := tfResources.List()
@@ -243,11 +618,13 @@ func getTfResourcesFromK8s(kubectlCLIConfig *KubectlConfig, d *schema.ResourceDa
 	var wg sync.WaitGroup
 	errChan := make(chan error, len(tfResourcesList))
 	resChan := make(chan interface{}, len(tfResourcesList))
+	driftChan := make(chan string, len(tfResourcesList))
 
 	var closeOnce sync.Once
 	closeChannels := func() {
 		close(errChan)
 		close(resChan)
+		close(driftChan)
 	}
 
 	defer func() {
@@ -258,7 +635,8 @@ func getTfResourcesFromK8s(kubectlCLIConfig *KubectlConfig, d *schema.ResourceDa
 		wg.Add(1)
 		go func(tfResource interface{}) {
 			defer wg.Done()
-			readResource(kubectlCLIConfig, tfResource, resChan, errChan)
+			readResource(kubectlCLIConfig, tfResource, options, audit,
+				resChan, driftChan, errChan)
 		}(tfResource)
 	}
 
@@ -271,13 +649,17 @@ func getTfResourcesFromK8s(kubectlCLIConfig *KubectlConfig, d *schema.ResourceDa
 	for routineResource := range resChan {
 		kubectlResources.Add(routineResource)
 	}
+	for selflink := range driftChan {
+		drifted = append(drifted, selflink)
+	}
 
 	commonResources := setIntersection(tfResources, kubectlResources)
-	return commonResources, errs
+	return commonResources, drifted, errs
 }
 
 func readResource(kubectlCLIConfig *KubectlConfig, tfResource interface{},
-	resChan chan<- interface{}, errChan chan<- error) {
+	options *driftOptions, audit *manifestAudit, resChan chan<- interface{},
+	driftChan chan<- string, errChan chan<- error) {
 
 	resourceObj, ok := tfResource.(map[string]interface{})
 	if !ok {
@@ -297,18 +679,34 @@ func readResource(kubectlCLIConfig *KubectlConfig, tfResource interface{},
 	log.Printf("[DEBUG] start refreshing resource %s in namespace %s",
 		resourceHandle, namespace)
 
+	span := kubectlCLIConfig.span.Child("read " + resourceHandle)
 	stdout := &bytes.Buffer{}
-	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
-	getCommand := commandFactory.CreateGetByHandleCommand(
+	commandFactory := &CLICommandFactory{
+		KubectlConfig: kubectlCLIConfig.withSpan(span)}
+	getCommand := commandFactory.CreateGetJSONByHandleCommand(
 		resourceHandle, namespace, stdout)
 
-	if err := getCommand.RunCommand(); err != nil {
+	start := time.Now()
+	err := getCommand.RunCommand()
+	span.End(err)
+	uid, _ := resourceObj["uid"].(string)
+	audit.record("read", auditStateObject(resourceObj), namespace, uid, start,
+		err)
+	if err != nil {
 		errChan <- err
 		return
 	}
 
 	if strings.TrimSpace(stdout.String()) != "" {
 		resChan <- tfResource
+
+		content, _ := resourceObj["content"].(string)
+		drifted, err := hasDrifted(content, stdout.String(), options)
+		if err != nil {
+			errChan <- err
+		} else if drifted {
+			driftChan <- selflink
+		}
 	}
 	log.Printf("[DEBUG] end refreshing resource %s in namespace %s",
 		resourceHandle, namespace)
@@ -368,7 +766,8 @@ func resourceManifestExists(d *schema.ResourceData, m interface{}) (bool, error)
 	return false, nil
 }
 
-func deleteResources(manifestResources *schema.Set,
+func deleteResources(manifestResources *schema.Set, owner *manifestOwner,
+	audit *manifestAudit, protected *protectedObjects, allowCRDDataLoss bool,
 	kubectlCLIConfig *KubectlConfig) error {
 
 	manifestResourcesList := manifestResources.List()
@@ -388,11 +787,31 @@ func deleteResources(manifestResources *schema.Set,
 		if !ok {
 			return fmt.Errorf("invalid resource id: %s", selflink)
 		}
+		uid, _ := tfResource["uid"].(string)
+		obj := auditStateObject(tfResource)
+		if protected.protects(obj, namespace) {
+			log.Printf("[WARN] not deleting protected object %s", selflink)
+			audit.record("orphan", obj, namespace, uid, time.Now(), nil)
+			continue
+		}
+		err := owner.checkHandle(resourceHandle, namespace, kubectlCLIConfig)
+		if err != nil {
+			return err
+		}
+		if obj.Kind() == "CustomResourceDefinition" && !allowCRDDataLoss {
+			err := checkCRDInstances(obj.Name(), owner, kubectlCLIConfig)
+			if err != nil {
+				return err
+			}
+		}
 		commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
 		deleteCommand := commandFactory.CreateDeleteByHandleCommand(
 			resourceHandle, namespace)
 
-		err := deleteCommand.RunCommand()
+		start := time.Now()
+		err = deleteCommand.RunCommand()
+		audit.record("delete", auditStateObject(tfResource), namespace, uid,
+			start, err)
 		if err != nil {
 			return err
 		}
@@ -401,56 +820,417 @@ func deleteResources(manifestResources *schema.Set,
 	return nil
 }
 
-func updateResources(manifestResources []string, namespace string,
-	kubectlCLIConfig *KubectlConfig) (*schema.Set, error) {
+// Read access to the arguments of a kubectl_manifest, shared by the resource
+// data of its operations and the resource diff of its plans
+type manifestArguments interface {
+	Get(key string) interface{}
+}
 
-	tfResources := schema.NewSet(HashResource, []interface{}{})
+// Splits the manifest into its documents, building the kustomization first
+// when kustomize_path is set
+func manifestDocuments(d manifestArguments,
+	kubectlCLIConfig *KubectlConfig) ([]string, error) {
+
+	content := d.Get("content").(string)
+	if path := d.Get("kustomize_path").(string); path != "" {
+		var err error
+		if content, err = buildKustomization(path, kubectlCLIConfig); err != nil {
+			return nil, err
+		}
+	} else if content == "" {
+		return nil, errors.New("one of content or kustomize_path must be set")
+	}
+	return resource.SplitYAMLDocument(content)
+}
+
+// Rewrites the manifest documents before they get applied
+func prepareManifestResources(d manifestArguments, config *Config,
+	owner *manifestOwner, manifestResources []string,
+	kubectlCLIConfig *KubectlConfig) ([]string, error) {
+
+	var err error
+
+	if d.Get("convert_deprecated_apis").(bool) {
+		manifestResources, err = convertDeprecatedAPIs(manifestResources,
+			kubectlCLIConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if namespace := d.Get("namespace").(string); namespace != "" {
+		apiResources, err := discoverAPIResources(kubectlCLIConfig)
+		if err != nil {
+			return nil, fmt.Errorf(
+				"error while discovering api resources: %s", err)
+		}
+		manifestResources, err = injectNamespace(manifestResources, namespace,
+			d.Get("override_namespace").(bool), namespacedKinds(apiResources))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	labels, annotations := documentMetadata(config, d)
+	manifestResources, err = injectMetadata(manifestResources, labels,
+		annotations, d.Get("label_pod_templates").(bool))
+	if err != nil {
+		return nil, err
+	}
+	return owner.stamp(manifestResources)
+}
+
+// Rewrites the documents using deprecated api versions to the replacements
+// served by the cluster
+func convertDeprecatedAPIs(manifestResources []string,
+	kubectlCLIConfig *KubectlConfig) ([]string, error) {
+
+	stdout := &bytes.Buffer{}
 	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	apiVersionsCommand := commandFactory.CreateAPIVersionsCommand(stdout)
+
+	if err := apiVersionsCommand.RunCommand(); err != nil {
+		return nil, fmt.Errorf("error while listing api versions: %s", err)
+	}
+	served := make(map[string]bool)
+	for _, apiVersion := range strings.Fields(stdout.String()) {
+		served[apiVersion] = true
+	}
 
+	converted := make([]string, 0, len(manifestResources))
 	for _, manifestResource := range manifestResources {
-		applyCommand := commandFactory.CreateApplyManifestCommand(
-			manifestResource, namespace)
+		obj, err := resource.DecodeObject(manifestResource)
+		if err != nil {
+			return nil, err
+		}
+		deprecated := obj.APIVersion()
+		apiVersion, err := obj.ConvertDeprecatedAPI(served)
+		if err != nil {
+			return nil, err
+		}
+		if apiVersion == "" {
+			converted = append(converted, manifestResource)
+			continue
+		}
 
-		if err := applyCommand.RunCommand(); err != nil {
+		log.Printf("[WARN] converting %s %q from the deprecated api version "+
+			"%s to %s", obj.Kind(), obj.Name(), deprecated, apiVersion)
+		encoded, err := obj.Encode()
+		if err != nil {
 			return nil, err
 		}
+		converted = append(converted, encoded)
+	}
+	return converted, nil
+}
 
-		stdout := &bytes.Buffer{}
-		getCommand := commandFactory.CreateGetByManifestCommand(
-			manifestResource, namespace, stdout)
+// Merges the provider level default labels and annotations with the ones set
+// on the resource, the latter taking precedence
+func documentMetadata(config *Config, d manifestArguments) (
+	labels, annotations map[string]string) {
 
-		if err := getCommand.RunCommand(); err != nil {
+	labels = make(map[string]string)
+	annotations = make(map[string]string)
+
+	for k, v := range config.DefaultLabels {
+		labels[k] = v
+	}
+	for k, v := range expandStringMap(d.Get("labels").(map[string]interface{})) {
+		labels[k] = v
+	}
+	for k, v := range config.DefaultAnnotations {
+		annotations[k] = v
+	}
+	for k, v := range expandStringMap(
+		d.Get("annotations").(map[string]interface{})) {
+		annotations[k] = v
+	}
+	return labels, annotations
+}
+
+// Adds the labels and annotations to every document, and optionally to the
+// pod templates of workloads. Values set in the documents take precedence.
+func injectMetadata(manifestResources []string, labels,
+	annotations map[string]string, podTemplates bool) ([]string, error) {
+
+	if len(labels) == 0 && len(annotations) == 0 {
+		return manifestResources, nil
+	}
+
+	injected := make([]string, 0, len(manifestResources))
+	for _, manifestResource := range manifestResources {
+		obj, err := resource.DecodeObject(manifestResource)
+		if err != nil {
 			return nil, err
 		}
+		obj.MergeMetadata(labels, annotations)
+		if podTemplates {
+			obj.MergePodTemplateMetadata(labels, annotations)
+		}
+		encoded, err := obj.Encode()
+		if err != nil {
+			return nil, err
+		}
+		injected = append(injected, encoded)
+	}
+	return injected, nil
+}
+
+// Sets the namespace of the documents of namespaced kinds.
+//
+// Documents of cluster scoped kinds are left untouched, as well as documents
+// of kinds unknown to the cluster (i.e. defined by a CRD in the same manifest)
+// which get the namespace from the `-n` flag instead. A document already
+// targeting a different namespace is an error, unless override is set.
+func injectNamespace(manifestResources []string, namespace string,
+	override bool, namespaced map[string]bool) ([]string, error) {
+
+	injected := make([]string, 0, len(manifestResources))
+
+	for _, manifestResource := range manifestResources {
+		obj, err := resource.DecodeObject(manifestResource)
+		if err != nil {
+			return nil, err
+		}
+
+		isNamespaced, known := namespaced[groupKind(
+			obj.GroupVersionKind().Group, obj.Kind())]
+		if known && !isNamespaced {
+			injected = append(injected, manifestResource)
+			continue
+		}
+
+		switch obj.Namespace() {
+		case namespace:
+			injected = append(injected, manifestResource)
+			continue
+		case "":
+			if !known {
+				injected = append(injected, manifestResource)
+				continue
+			}
+		default:
+			if !override {
+				return nil, fmt.Errorf(
+					"%s %q sets namespace %q which conflicts with the "+
+						"resource namespace %q, set override_namespace to "+
+						"force it", obj.Kind(), obj.Name(), obj.Namespace(),
+					namespace)
+			}
+		}
 
-		var data resource.KubectlResponse
-		if err := json.Unmarshal(stdout.Bytes(), &data); err != nil {
-			return nil, fmt.Errorf("decoding response: %v", err)
+		obj.SetNamespace(namespace)
+		encoded, err := obj.Encode()
+		if err != nil {
+			return nil, err
 		}
+		injected = append(injected, encoded)
+	}
+	return injected, nil
+}
+
+func updateResources(manifestResources []string, namespace string,
+	owner *manifestOwner, audit *manifestAudit, protected *protectedObjects,
+	allowCRDDataLoss, redactSecrets, forceReplace bool,
+	kubectlCLIConfig *KubectlConfig) (*schema.Set, []appliedObject, error) {
+
+	tfResources := schema.NewSet(HashResource, []interface{}{})
+	applied := make([]appliedObject, 0, len(manifestResources))
 
-		if len(data.Items) > 1 {
-			return nil, fmt.Errorf("Expecting a single resource, found multiple")
+	for _, manifestResource := range manifestResources {
+		start := time.Now()
+		obj := auditObject(manifestResource)
+		span := kubectlCLIConfig.span.Child("apply " + obj.Kind() + "/" +
+			obj.Name())
+		applyConfig := kubectlCLIConfig.withSpan(span)
+		commandFactory := &CLICommandFactory{KubectlConfig: applyConfig}
+
+		fail := func(err error) (*schema.Set, []appliedObject, error) {
+			audit.record("apply", obj, namespace, "", start, err)
+			span.End(err)
+			return tfResources, applied, err
 		}
-		selflink := data.Items[0].Metadata.Selflink
-		if selflink == "" {
-			return nil, fmt.Errorf("could not parse self-link from response %s",
-				stdout.String(),
-			)
+
+		previous, err := getLiveObject(manifestResource, namespace,
+			applyConfig)
+		if err != nil {
+			return fail(err)
 		}
-		uid := data.Items[0].Metadata.UID
-		if uid == "" {
-			return nil, fmt.Errorf("could not parse uid from response %s",
-				stdout.String(),
-			)
+		if previous != nil {
+			if err := owner.check(previous); err != nil {
+				return fail(err)
+			}
 		}
 
+		applyOutput := &bytes.Buffer{}
+		applyCommand := commandFactory.CreateApplyManifestCommand(
+			manifestResource, namespace)
+		applyCommand.Stdout = applyOutput
+
+		err = applyCommand.RunCommand()
+		replaced := false
+		if err != nil && forceReplace && previous != nil &&
+			isImmutableError(err) {
+
+			log.Printf("[INFO] replacing %s %s, as its changes are immutable: %s",
+				obj.Kind(), obj.Name(), err)
+			applyOutput.Reset()
+			err = replaceObject(manifestResource, namespace, previous,
+				owner, protected, allowCRDDataLoss, applyOutput, audit,
+				applyConfig)
+			replaced = true
+		}
+		if err != nil {
+			return fail(err)
+		}
+		applied = append(applied, appliedObject{manifest: manifestResource,
+			namespace: namespace, previous: previous, replaced: replaced})
+
+		var selflink, uid string
+		if applyConfig.DryRun != "" {
+			// nothing was applied: the state records the object returned
+			// by the dry-run
+			result, err := resource.DecodeObjects(applyOutput.String())
+			if err != nil || len(result) != 1 {
+				return fail(fmt.Errorf(
+					"could not parse dry-run result from response %s",
+					applyOutput.String()))
+			}
+			applied[len(applied)-1].result = result[0]
+			selflink, uid = dryRunIdentity(result[0], previous, namespace)
+		} else {
+			selflink, uid, err = getAppliedIdentity(manifestResource,
+				namespace, applyConfig)
+			if err != nil {
+				return fail(err)
+			}
+		}
+		audit.record("apply", obj, namespace, uid, start, nil)
+		span.SetAttribute("k8s.uid", uid)
+		span.End(nil)
+
+		stateContent := manifestResource
+		if redactSecrets {
+			if stateContent, err = redactSecretData(manifestResource); err != nil {
+				return tfResources, applied, err
+			}
+		}
 		manifestResourceBase64 := base64.StdEncoding.EncodeToString(
-			[]byte(manifestResource))
+			[]byte(stateContent))
 		tfResources.Add(map[string]interface{}{"uid": uid, "selflink": selflink,
 			"content": manifestResourceBase64})
 	}
 
-	return tfResources, nil
+	return tfResources, applied, nil
+}
+
+// Describes how the manifest documents are compared with the live objects
+type driftOptions struct {
+	ignoreFields     []string
+	kindIgnoreFields map[string][]string
+	labels           map[string]string
+	annotations      map[string]string
+	podTemplates     bool
+}
+
+func newDriftOptions(config *Config, d *schema.ResourceData) *driftOptions {
+	labels, annotations := documentMetadata(config, d)
+	return &driftOptions{
+		ignoreFields: expandStringList(
+			d.Get("ignore_fields").([]interface{})),
+		kindIgnoreFields: config.IgnoreFields,
+		labels:           labels,
+		annotations:      annotations,
+		podTemplates:     d.Get("label_pod_templates").(bool),
+	}
+}
+
+// Compares the manifest document stored in the state with the live object.
+//
+// Only the fields set in the manifest are compared, after removing from both
+// documents the fields ignored at resource level and the provider level
+// defaults for the document kind. The current labels and annotations are
+// merged into the manifest, so that changing them re-applies the objects.
+func hasDrifted(base64Content, liveContent string, options *driftOptions) (
+	bool, error) {
+
+	content, err := base64.StdEncoding.DecodeString(base64Content)
+	if err != nil {
+		return false, fmt.Errorf("decoding resource content: %v", err)
+	}
+	desired, err := resource.DecodeObject(string(content))
+	if err != nil {
+		return false, fmt.Errorf("decoding resource content: %v", err)
+	}
+	live, err := resource.DecodeObject(liveContent)
+	if err != nil {
+		return false, fmt.Errorf("decoding response: %v", err)
+	}
+
+	// Secrets are compared through the hash of their values, the state only
+	// holding the hash, keyed by the salt stored along with it, when
+	// redact_secrets is set
+	salt := desired.SecretDataSalt()
+	if salt == nil {
+		if salt, err = resource.NewSecretSalt(); err != nil {
+			return false, err
+		}
+	}
+	if err := desired.HashSecretData(salt); err != nil {
+		return false, fmt.Errorf("decoding resource content: %v", err)
+	}
+	if err := live.HashSecretData(salt); err != nil {
+		return false, fmt.Errorf("decoding response: %v", err)
+	}
+
+	desired.MergeMetadata(options.labels, options.annotations)
+	if options.podTemplates {
+		desired.MergePodTemplateMetadata(options.labels, options.annotations)
+	}
+
+	fields := make([]string, 0)
+	fields = append(fields, options.ignoreFields...)
+	fields = append(fields, options.kindIgnoreFields[desired.Kind()]...)
+	for _, field := range fields {
+		segments, err := resource.ParseFieldPath(field)
+		if err != nil {
+			return false, fmt.Errorf("invalid ignore_fields entry %q: %v",
+				field, err)
+		}
+		desired.RemoveField(segments)
+		live.RemoveField(segments)
+	}
+	return !resource.IsSubset(desired, live), nil
+}
+
+// Replaces the values of Secret documents with their hash, keyed by a salt
+// generated for the document
+func redactSecretData(manifestResource string) (string, error) {
+	obj, err := resource.DecodeObject(manifestResource)
+	if err != nil {
+		return "", err
+	}
+	if obj.Kind() != "Secret" {
+		return manifestResource, nil
+	}
+	salt, err := resource.NewSecretSalt()
+	if err != nil {
+		return "", err
+	}
+	if err := obj.HashSecretData(salt); err != nil {
+		return "", err
+	}
+	return obj.Encode()
+}
+
+func expandStringList(list []interface{}) []string {
+	strs := make([]string, 0, len(list))
+	for _, v := range list {
+		if str, ok := v.(string); ok {
+			strs = append(strs, str)
+		}
+	}
+	return strs
 }
 
 func resourceFromSelflink(s string) (resource, namespace string, ok bool) {
@@ -481,6 +1261,46 @@ func setIntersection(set1, set2 *schema.Set) *schema.Set {
 	return intersection
 }
 
+func setUnion(set1, set2 *schema.Set) *schema.Set {
+	union := schema.NewSet(HashResource, []interface{}{})
+	for _, elem := range set1.List() {
+		union.Add(elem)
+	}
+	for _, elem := range set2.List() {
+		union.Add(elem)
+	}
+	return union
+}
+
+// Lists the resources of set1 whose object is not part of set2. Replaced
+// objects keep their self link but get a new uid: their previous entry is
+// left out, as deleting it would delete the replacement.
+func setObjectDifference(set1, set2 *schema.Set) *schema.Set {
+	objects := make(map[string]bool)
+	for _, elem := range set2.List() {
+		objects[resourceObjectKey(elem)] = true
+	}
+
+	difference := schema.NewSet(HashResource, []interface{}{})
+	for _, elem := range set1.List() {
+		if !objects[resourceObjectKey(elem)] {
+			difference.Add(elem)
+		}
+	}
+	return difference
+}
+
+// Identifies the object of a resource by its namespace and resource handle
+func resourceObjectKey(elem interface{}) string {
+	tfResource, _ := elem.(map[string]interface{})
+	selflink, _ := tfResource["selflink"].(string)
+	handle, namespace, ok := resourceFromSelflink(selflink)
+	if !ok {
+		return selflink
+	}
+	return namespace + "/" + handle
+}
+
 func setDifference(set1, set2 *schema.Set) *schema.Set {
 	difference := schema.NewSet(HashResource, []interface{}{})
 	set1Elems := set1.List()
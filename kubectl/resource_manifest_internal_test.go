@@ -0,0 +1,224 @@
+package kubectl
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Drift detection", func() {
+
+	const desired = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      containers:
+      - name: web
+        image: web:1.0.0
+        env:
+        - name: DEBUG
+          value: ""
+        resources:
+          requests:
+            cpu: 0.5
+            memory: 1024Mi
+`
+	const live = `{"apiVersion": "apps/v1", "kind": "Deployment",
+		"metadata": {"name": "web", "uid": "5d3c0f7e"},
+		"spec": {"replicas": 1, "template": {"spec": {"containers": [{
+			"name": "web", "image": "%s", "env": [{"name": "DEBUG"}],
+			"resources": {"requests": {"cpu": "500m", "memory": "1Gi"}}}]}}}}`
+
+	options := &driftOptions{}
+	content := base64.StdEncoding.EncodeToString([]byte(desired))
+
+	It("Should not report values canonicalized by the api server", func() {
+		drifted, err := hasDrifted(content,
+			fmt.Sprintf(live, "web:1.0.0"), options)
+		Expect(err).To(BeNil())
+		Expect(drifted).To(BeFalse())
+	})
+
+	It("Should compare redacted Secrets with the live values", func() {
+		redacted, err := redactSecretData("apiVersion: v1\nkind: Secret\n" +
+			"metadata:\n  name: db\nstringData:\n  password: hunter2\n")
+		Expect(err).To(BeNil())
+		Expect(redacted).NotTo(ContainSubstring("hunter2"))
+		content := base64.StdEncoding.EncodeToString([]byte(redacted))
+
+		drifted, err := hasDrifted(content, `{"apiVersion": "v1",
+			"kind": "Secret", "metadata": {"name": "db"},
+			"data": {"password": "aHVudGVyMg=="}}`, options)
+		Expect(err).To(BeNil())
+		Expect(drifted).To(BeFalse())
+
+		drifted, err = hasDrifted(content, `{"apiVersion": "v1",
+			"kind": "Secret", "metadata": {"name": "db"},
+			"data": {"password": "aHVudGVyMw=="}}`, options)
+		Expect(err).To(BeNil())
+		Expect(drifted).To(BeTrue())
+	})
+
+	It("Should report changed values", func() {
+		drifted, err := hasDrifted(content,
+			fmt.Sprintf(live, "web:1.1.0"), options)
+		Expect(err).To(BeNil())
+		Expect(drifted).To(BeTrue())
+	})
+})
+
+var _ = Describe("Replaced objects", func() {
+
+	const webSelflink = "/apis/apps/v1/namespaces/default/deployments/web"
+	const workerSelflink = "/apis/apps/v1/namespaces/default/deployments/worker"
+
+	tfResource := func(selflink, uid string) map[string]interface{} {
+		return map[string]interface{}{"selflink": selflink, "uid": uid,
+			"content": ""}
+	}
+
+	It("Should not delete the replacement of a replaced object", func() {
+		// web is replaced, getting a new uid, and worker removed from the
+		// manifest
+		tfOldResources := schema.NewSet(HashResource, []interface{}{
+			tfResource(webSelflink, "1a2b"),
+			tfResource(workerSelflink, "3c4d")})
+		tfResources := schema.NewSet(HashResource, []interface{}{
+			tfResource(webSelflink, "5e6f")})
+
+		toDelete := setObjectDifference(tfOldResources, tfResources)
+		Expect(toDelete.List()).To(ConsistOf(
+			tfResource(workerSelflink, "3c4d")))
+	})
+
+	It("Should not keep the previous entry of a replaced object", func() {
+		tfOldResources := schema.NewSet(HashResource, []interface{}{
+			tfResource(webSelflink, "1a2b"),
+			tfResource(workerSelflink, "3c4d")})
+		tfResources := schema.NewSet(HashResource, []interface{}{
+			tfResource(webSelflink, "5e6f")})
+
+		kept := setUnion(tfResources,
+			setObjectDifference(tfOldResources, tfResources))
+		Expect(kept.List()).To(ConsistOf(tfResource(webSelflink, "5e6f"),
+			tfResource(workerSelflink, "3c4d")))
+	})
+})
+
+var _ = Describe("Namespace injection", func() {
+
+	namespaced := namespacedKinds(resource.ParseAPIResources(`
+NAME                  SHORTNAMES   APIVERSION                     NAMESPACED   KIND
+configmaps            cm           v1                             true         ConfigMap
+namespaces            ns           v1                             false        Namespace
+roles                              rbac.authorization.k8s.io/v1   true         Role
+roles                              iam.example.com/v1             false        Role
+`))
+
+	cases := []struct {
+		description string
+		document    string
+		override    bool
+		// expected namespace, empty when left unset
+		namespace string
+		fails     bool
+	}{
+		{"Should set the namespace of namespaced kinds",
+			"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n",
+			false, "production", false},
+		{"Should keep a matching namespace",
+			"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n" +
+				"  namespace: production\n", false, "production", false},
+		{"Should reject a conflicting namespace",
+			"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n" +
+				"  namespace: staging\n", false, "", true},
+		{"Should override a conflicting namespace when asked to",
+			"apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: settings\n" +
+				"  namespace: staging\n", true, "production", false},
+		{"Should leave cluster scoped kinds untouched",
+			"apiVersion: v1\nkind: Namespace\nmetadata:\n  name: production\n",
+			false, "", false},
+		{"Should leave unknown kinds to the -n flag",
+			"apiVersion: example.com/v1\nkind: Widget\nmetadata:\n  name: gadget\n",
+			false, "", false},
+		{"Should set the namespace of namespaced kinds of a group",
+			"apiVersion: rbac.authorization.k8s.io/v1\nkind: Role\n" +
+				"metadata:\n  name: reader\n", false, "production", false},
+		{"Should leave cluster scoped kinds of another group untouched",
+			"apiVersion: iam.example.com/v1\nkind: Role\n" +
+				"metadata:\n  name: reader\n", false, "", false},
+	}
+
+	for _, c := range cases {
+		c := c
+		It(c.description, func() {
+			injected, err := injectNamespace([]string{c.document},
+				"production", c.override, namespaced)
+			if c.fails {
+				Expect(err).NotTo(BeNil())
+				Expect(err.Error()).To(ContainSubstring("override_namespace"))
+				return
+			}
+			Expect(err).To(BeNil())
+			obj, err := resource.DecodeObject(injected[0])
+			Expect(err).To(BeNil())
+			Expect(obj.Namespace()).To(Equal(c.namespace))
+		})
+	}
+})
+
+var _ = Describe("Destroy policies", func() {
+
+	const manifest = `{"apiVersion": "v1", "kind": "ConfigMap",
+		"metadata": {"name": "settings", "namespace": "default"}}`
+
+	var kubectl *fakeKubectl
+	var d *schema.ResourceData
+
+	BeforeEach(func() {
+		kubectl = installFakeKubectl()
+		d = resourceManifest().Data(nil)
+		d.SetId("settings")
+		Expect(d.Set("name", "settings")).To(Succeed())
+		Expect(d.Set("resources", []interface{}{map[string]interface{}{
+			"selflink": "/api/v1/namespaces/default/configmaps/settings",
+			"uid":      "5d3c0f7e",
+			"content":  base64.StdEncoding.EncodeToString([]byte(manifest)),
+		}})).To(Succeed())
+	})
+
+	AfterEach(func() {
+		kubectl.restore()
+	})
+
+	It("Should delete the objects by default", func() {
+		Expect(resourceManifestDelete(d, &Config{})).To(Succeed())
+		Expect(kubectl.commands()).To(ContainElement(
+			HavePrefix("delete")))
+	})
+
+	It("Should refuse to destroy protected manifests", func() {
+		Expect(d.Set("deletion_protection", true)).To(Succeed())
+
+		err := resourceManifestDelete(d, &Config{})
+		Expect(err).NotTo(BeNil())
+		Expect(err.Error()).To(ContainSubstring("deletion_protection"))
+		Expect(kubectl.commands()).To(BeEmpty())
+	})
+
+	It("Should leave the objects of orphaned manifests", func() {
+		Expect(d.Set("on_destroy", OnDestroyOrphan)).To(Succeed())
+
+		Expect(resourceManifestDelete(d, &Config{})).To(Succeed())
+		Expect(kubectl.commands()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,282 @@
+package kubectl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+func resourcePatch() *schema.Resource {
+	return &schema.Resource{
+		Create: resourcePatchCreate,
+		Read:   resourcePatchRead,
+		Update: resourcePatchUpdate,
+		Delete: resourcePatchDelete,
+
+		Schema: map[string]*schema.Schema{
+			"api_version": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"kind": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"name": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"namespace": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"patch_type": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      resource.StrategicPatch,
+				ValidateFunc: validatePatchType,
+			},
+			"patch": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+			},
+			"restore_on_destroy": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"patched_fields": &schema.Schema{
+				Type:     schema.TypeList,
+				Computed: true,
+				Elem:     &schema.Schema{Type: schema.TypeString},
+			},
+			// values of the patched fields captured before patching
+			"original": &schema.Schema{
+				Type:      schema.TypeString,
+				Computed:  true,
+				Sensitive: true,
+			},
+		},
+	}
+}
+
+func validatePatchType(v interface{}, k string) ([]string, []error) {
+	switch v.(string) {
+	case resource.StrategicPatch, resource.MergePatch, resource.JSONPatch:
+		return nil, nil
+	}
+	return nil, []error{fmt.Errorf("%s must be one of %s, %s or %s, got %q",
+		k, resource.StrategicPatch, resource.MergePatch, resource.JSONPatch,
+		v.(string))}
+}
+
+// Builds the handle of the patched object, i.e. `deployment.v1.apps/coredns`
+func patchResourceHandle(d *schema.ResourceData) string {
+	return resource.ResourceTypeArg(d.Get("api_version").(string),
+		d.Get("kind").(string)) + "/" + d.Get("name").(string)
+}
+
+// Fetches the patched object, returning nil when it does not exist
+func getPatchedObject(d *schema.ResourceData,
+	kubectlCLIConfig *KubectlConfig) (resource.Object, error) {
+
+	stdout := &bytes.Buffer{}
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	getCommand := commandFactory.CreateGetJSONByHandleCommand(
+		patchResourceHandle(d), d.Get("namespace").(string), stdout)
+
+	if err := getCommand.RunCommand(); err != nil {
+		return nil, err
+	}
+	liveObjects, err := resource.DecodeObjects(stdout.String())
+	if err != nil {
+		return nil, fmt.Errorf("decoding response: %v", err)
+	}
+	if len(liveObjects) == 0 {
+		return nil, nil
+	}
+	return liveObjects[0], nil
+}
+
+// The steps involved in patching an object are:
+//  1. fetching the object, which must already exist
+//  2. capturing the values of the fields modified by the patch, so that they
+//     can be restored on destroy
+//  3. applying the patch
+func resourcePatchCreate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+
+	kubectlCLIConfig, err := NewKubectlConfig(config)
+	if err != nil {
+		return fmt.Errorf("error while processing kubeconfig file: %s", err)
+	}
+	defer kubectlCLIConfig.Cleanup()
+
+	if err := applyPatch(d, kubectlCLIConfig); err != nil {
+		return err
+	}
+	d.SetId(d.Get("namespace").(string) + "/" + patchResourceHandle(d))
+	return nil
+}
+
+func resourcePatchUpdate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+
+	kubectlCLIConfig, err := NewKubectlConfig(config)
+	if err != nil {
+		return fmt.Errorf("error while processing kubeconfig file: %s", err)
+	}
+	defer kubectlCLIConfig.Cleanup()
+
+	if d.HasChange("patch") || d.HasChange("patch_type") {
+		return applyPatch(d, kubectlCLIConfig)
+	}
+	return nil
+}
+
+func applyPatch(d *schema.ResourceData, kubectlCLIConfig *KubectlConfig) error {
+	patchType := d.Get("patch_type").(string)
+	fields, err := resource.ParsePatchFields(patchType, d.Get("patch").(string))
+	if err != nil {
+		return err
+	}
+	patch, err := resource.DecodePatch(d.Get("patch").(string))
+	if err != nil {
+		return err
+	}
+
+	live, err := getPatchedObject(d, kubectlCLIConfig)
+	if err != nil {
+		return err
+	}
+	if live == nil {
+		return fmt.Errorf("cannot patch %s: object not found",
+			patchResourceHandle(d))
+	}
+
+	// fields patched by a previous version of the patch keep the value
+	// captured before they were first patched
+	originals := &resource.PatchOriginals{}
+	if original := d.Get("original").(string); original != "" {
+		if err := json.Unmarshal([]byte(original), originals); err != nil {
+			return fmt.Errorf("decoding original values: %v", err)
+		}
+	}
+	originals.Capture(fields, live)
+	original, err := json.Marshal(originals)
+	if err != nil {
+		return err
+	}
+
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	patchCommand := commandFactory.CreatePatchByHandleCommand(
+		patchResourceHandle(d), d.Get("namespace").(string), patchType, patch)
+	if err := patchCommand.RunCommand(); err != nil {
+		return err
+	}
+
+	patchedFields := make([]string, 0, len(fields))
+	for _, field := range fields {
+		patchedFields = append(patchedFields, resource.Pointer(field.Path))
+	}
+	if err := d.Set("patched_fields", patchedFields); err != nil {
+		return err
+	}
+	return d.Set("original", string(original))
+}
+
+// Checks that every field set by the patch still holds the patched value,
+// forcing the patch to be applied again otherwise
+func resourcePatchRead(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+
+	kubectlCLIConfig, err := NewKubectlConfig(config)
+	if err != nil {
+		return fmt.Errorf("error while processing kubeconfig file: %s", err)
+	}
+	defer kubectlCLIConfig.Cleanup()
+
+	live, err := getPatchedObject(d, kubectlCLIConfig)
+	if err != nil {
+		return err
+	}
+	if live == nil {
+		log.Printf("[DEBUG] patched object %s not found", d.Id())
+		d.SetId("")
+		return nil
+	}
+
+	patchType := d.Get("patch_type").(string)
+	fields, err := resource.ParsePatchFields(patchType, d.Get("patch").(string))
+	if err != nil {
+		return err
+	}
+	for _, field := range fields {
+		if !resource.PatchFieldApplied(field, live,
+			patchType == resource.StrategicPatch) {
+
+			log.Printf("[INFO] field %s of %s drifted from the patch",
+				resource.Pointer(field.Path), d.Id())
+			// forces a diff on the patch so that it gets applied again
+			d.Set("patch", "")
+			break
+		}
+	}
+	return nil
+}
+
+// Removes the patch from the state, restoring the values captured before the
+// patch when restore_on_destroy is set
+func resourcePatchDelete(d *schema.ResourceData, m interface{}) error {
+	if !d.Get("restore_on_destroy").(bool) {
+		return nil
+	}
+
+	config := m.(*Config)
+
+	kubectlCLIConfig, err := NewKubectlConfig(config)
+	if err != nil {
+		return fmt.Errorf("error while processing kubeconfig file: %s", err)
+	}
+	defer kubectlCLIConfig.Cleanup()
+
+	live, err := getPatchedObject(d, kubectlCLIConfig)
+	if err != nil || live == nil {
+		return err
+	}
+
+	original := d.Get("original").(string)
+	if original == "" {
+		return nil
+	}
+	originals := &resource.PatchOriginals{}
+	if err := json.Unmarshal([]byte(original), originals); err != nil {
+		return fmt.Errorf("decoding original values: %v", err)
+	}
+	restorePatch, err := originals.RestorePatch(live)
+	if err != nil || restorePatch == "" {
+		return err
+	}
+
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	patchCommand := commandFactory.CreatePatchByHandleCommand(
+		patchResourceHandle(d), d.Get("namespace").(string),
+		resource.JSONPatch, restorePatch)
+	if err := patchCommand.RunCommand(); err != nil {
+		return err
+	}
+	if config.DryRun != "" {
+		return dryRunDestroyError(config.DryRun, fmt.Sprintf(
+			"the patched values of %s were not restored",
+			patchResourceHandle(d)))
+	}
+	return nil
+}
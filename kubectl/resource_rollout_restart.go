@@ -0,0 +1,215 @@
+package kubectl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// pod template annotation set by `kubectl rollout restart`
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+func resourceRolloutRestart() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceRolloutRestartCreate,
+		Read:   schema.Noop,
+		Update: resourceRolloutRestartUpdate,
+		Delete: schema.RemoveFromState,
+
+		CustomizeDiff: func(d *schema.ResourceDiff, m interface{}) error {
+			if d.Id() != "" && d.HasChange("triggers") {
+				return d.SetNewComputed("restarted_at")
+			}
+			return nil
+		},
+
+		Schema: map[string]*schema.Schema{
+			"api_version": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			// Deployment, StatefulSet or DaemonSet
+			"kind": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"name": &schema.Schema{
+				Type:          schema.TypeString,
+				Optional:      true,
+				ForceNew:      true,
+				ConflictsWith: []string{"selector"},
+			},
+			// label selector of the workloads
+			"selector": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"namespace": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			// arbitrary values, such as content hashes, restarting the
+			// workloads when they change
+			"triggers": &schema.Schema{
+				Type:     schema.TypeMap,
+				Required: true,
+			},
+			"wait_for_rollout": &schema.Schema{
+				Type:     schema.TypeBool,
+				Optional: true,
+				Default:  false,
+			},
+			"timeout": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "5m",
+				ValidateFunc: validateDuration,
+			},
+			// time of the last restart, empty until the triggers change
+			"restarted_at": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+// Records the triggers: the workloads are created with the current values,
+// so they are only restarted once the triggers change
+func resourceRolloutRestartCreate(d *schema.ResourceData, m interface{}) error {
+	name := d.Get("name").(string)
+	selector := d.Get("selector").(string)
+	if name == "" && selector == "" {
+		return fmt.Errorf("one of name or selector must be set")
+	}
+
+	d.SetId(strings.Join([]string{d.Get("namespace").(string),
+		resource.ResourceTypeArg(d.Get("api_version").(string),
+			d.Get("kind").(string)), name, selector}, "/"))
+	return d.Set("restarted_at", "")
+}
+
+// The steps involved in restarting the workloads are:
+//  1. listing the workloads, by name or label selector
+//  2. patching the restart annotation of their pod template, like
+//     `kubectl rollout restart` does
+//  3. waiting for their rollout when wait_for_rollout is set
+func resourceRolloutRestartUpdate(d *schema.ResourceData, m interface{}) error {
+	if !d.HasChange("triggers") {
+		return nil
+	}
+
+	config := m.(*Config)
+
+	kubectlCLIConfig, err := NewKubectlConfig(config)
+	if err != nil {
+		return fmt.Errorf("error while processing kubeconfig file: %s", err)
+	}
+	defer kubectlCLIConfig.Cleanup()
+
+	namespace := d.Get("namespace").(string)
+	handles, err := rolloutTargets(d, kubectlCLIConfig)
+	if err != nil {
+		return err
+	}
+
+	restartedAt := time.Now().UTC().Format(time.RFC3339)
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{
+						restartedAtAnnotation: restartedAt,
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	for _, handle := range handles {
+		log.Printf("[INFO] restarting %s in namespace %q", handle, namespace)
+		patchCommand := commandFactory.CreatePatchByHandleCommand(handle,
+			namespace, resource.MergePatch, string(patch))
+		if err := patchCommand.RunCommand(); err != nil {
+			return err
+		}
+	}
+	if config.DryRun != "" {
+		// nothing was restarted: the previous triggers are kept in the
+		// state, so that the workloads are restarted on the next apply
+		d.Partial(true)
+		return nil
+	}
+	if err := d.Set("restarted_at", restartedAt); err != nil {
+		return err
+	}
+
+	if !d.Get("wait_for_rollout").(bool) {
+		return nil
+	}
+	timeout, err := time.ParseDuration(d.Get("timeout").(string))
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(timeout)
+	for _, handle := range handles {
+		remaining := deadline.Sub(time.Now())
+		if remaining < time.Second {
+			return fmt.Errorf("timeout while waiting for the rollout of %s",
+				handle)
+		}
+		statusCommand := commandFactory.CreateRolloutStatusCommand(handle,
+			namespace, remaining-remaining%time.Second)
+		if err := statusCommand.RunCommand(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lists the handles of the restarted workloads, i.e. `deployment.v1.apps/web`
+func rolloutTargets(d *schema.ResourceData,
+	kubectlCLIConfig *KubectlConfig) ([]string, error) {
+
+	resourceType := resource.ResourceTypeArg(d.Get("api_version").(string),
+		d.Get("kind").(string))
+	if name := d.Get("name").(string); name != "" {
+		return []string{resourceType + "/" + name}, nil
+	}
+
+	stdout := &bytes.Buffer{}
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	getCommand := commandFactory.CreateGetJSONBySelectorCommand(resourceType,
+		d.Get("namespace").(string), d.Get("selector").(string), "", stdout)
+	if err := getCommand.RunCommand(); err != nil {
+		return nil, err
+	}
+	workloads, err := resource.DecodeObjects(stdout.String())
+	if err != nil {
+		return nil, fmt.Errorf("decoding response: %v", err)
+	}
+
+	handles := make([]string, 0, len(workloads))
+	for _, workload := range workloads {
+		handles = append(handles, resourceType+"/"+workload.Name())
+	}
+	if len(handles) == 0 {
+		log.Printf("[WARN] no %s matching %q to restart", resourceType,
+			d.Get("selector").(string))
+	}
+	return handles, nil
+}
@@ -0,0 +1,45 @@
+package kubectl
+
+import (
+	"github.com/hashicorp/terraform/terraform"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Rollout restarts", func() {
+
+	var kubectl *fakeKubectl
+
+	BeforeEach(func() {
+		kubectl = installFakeKubectl()
+	})
+
+	AfterEach(func() {
+		kubectl.restore()
+	})
+
+	It("Should keep the previous triggers of dry runs", func() {
+		state := &terraform.InstanceState{ID: "/deployment/web/",
+			Attributes: map[string]string{
+				"id":               "/deployment/web/",
+				"kind":             "Deployment",
+				"name":             "web",
+				"triggers.%":       "1",
+				"triggers.image":   "1.0.0",
+				"wait_for_rollout": "false",
+				"timeout":          "5m",
+				"restarted_at":     "",
+			}}
+
+		state, err := applyResource(resourceRolloutRestart(), state,
+			map[string]interface{}{"kind": "Deployment", "name": "web",
+				"triggers": map[string]interface{}{"image": "1.1.0"}},
+			&Config{DryRun: DryRunServer})
+		Expect(err).To(BeNil())
+		Expect(state.Attributes["triggers.image"]).To(Equal("1.0.0"))
+		Expect(kubectl.commands()).To(HaveLen(1))
+		Expect(kubectl.commands()[0]).To(HavePrefix("patch deployment/web"))
+		Expect(kubectl.commands()[0]).To(ContainSubstring("--dry-run=server"))
+	})
+})
@@ -0,0 +1,236 @@
+package kubectl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Typeform/terraform-provider-kubectl/kubectl/resource"
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+// interval between two waits on objects which do not exist yet
+const waitPollInterval = 5 * time.Second
+
+// errors of `kubectl wait` meaning that the objects do not exist yet
+var waitNotFoundErrors = []string{
+	"(NotFound)",
+	"no matching resources found",
+	"doesn't have a resource type",
+}
+
+func resourceWait() *schema.Resource {
+	return &schema.Resource{
+		Create: resourceWaitCreate,
+		Read:   schema.Noop,
+		Delete: schema.RemoveFromState,
+
+		Schema: map[string]*schema.Schema{
+			"api_version": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"kind": &schema.Schema{
+				Type:     schema.TypeString,
+				Required: true,
+				ForceNew: true,
+			},
+			"name": &schema.Schema{
+				Type:          schema.TypeString,
+				Optional:      true,
+				ForceNew:      true,
+				ConflictsWith: []string{"selector"},
+			},
+			// label selector of the objects
+			"selector": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			"namespace": &schema.Schema{
+				Type:     schema.TypeString,
+				Optional: true,
+				ForceNew: true,
+			},
+			// condition=<name>[=<value>], delete or jsonpath=<expression>=<value>
+			"for": &schema.Schema{
+				Type:         schema.TypeString,
+				Required:     true,
+				ForceNew:     true,
+				ValidateFunc: validateWaitFor,
+			},
+			"timeout": &schema.Schema{
+				Type:         schema.TypeString,
+				Optional:     true,
+				Default:      "5m",
+				ForceNew:     true,
+				ValidateFunc: validateDuration,
+			},
+			// the object, or the list of objects matching the selector, once
+			// the condition is met
+			"json": &schema.Schema{
+				Type:     schema.TypeString,
+				Computed: true,
+			},
+		},
+	}
+}
+
+func validateWaitFor(v interface{}, k string) ([]string, []error) {
+	condition := v.(string)
+	if condition == "delete" ||
+		strings.HasPrefix(condition, "condition=") && len(condition) > 10 ||
+		strings.HasPrefix(condition, "jsonpath=") && len(condition) > 9 {
+		return nil, nil
+	}
+	return nil, []error{fmt.Errorf(
+		"%s must be one of delete, condition=<name> or jsonpath=<expression>, got %q",
+		k, condition)}
+}
+
+func validateDuration(v interface{}, k string) ([]string, []error) {
+	duration, err := time.ParseDuration(v.(string))
+	if err != nil {
+		return nil, []error{fmt.Errorf("%s is not a valid duration: %v", k, err)}
+	}
+	if duration <= 0 {
+		return nil, []error{fmt.Errorf("%s must be positive, got %q", k,
+			v.(string))}
+	}
+	return nil, nil
+}
+
+// Waits for the condition to be met by the object, or by the objects matching
+// the selector, and records them in the state.
+//
+// The objects are watched by `kubectl wait`. Objects which do not exist yet,
+// such as the objects created by an operator or whose CRD is not installed
+// yet, are waited for until the timeout expires.
+func resourceWaitCreate(d *schema.ResourceData, m interface{}) error {
+	config := m.(*Config)
+
+	kubectlCLIConfig, err := NewKubectlConfig(config)
+	if err != nil {
+		return fmt.Errorf("error while processing kubeconfig file: %s", err)
+	}
+	defer kubectlCLIConfig.Cleanup()
+
+	resourceArg := resource.ResourceTypeArg(d.Get("api_version").(string),
+		d.Get("kind").(string))
+	name := d.Get("name").(string)
+	selector := d.Get("selector").(string)
+	namespace := d.Get("namespace").(string)
+	condition := d.Get("for").(string)
+	if name == "" && selector == "" {
+		return fmt.Errorf("one of name or selector must be set")
+	}
+	if name != "" {
+		resourceArg += "/" + name
+	}
+	timeout, err := time.ParseDuration(d.Get("timeout").(string))
+	if err != nil {
+		return err
+	}
+
+	id := strings.Join([]string{namespace, resourceArg, selector, condition},
+		"/")
+	if config.DryRun != "" {
+		// the objects waited for are usually the ones the dry-run did not
+		// create
+		log.Printf("[INFO] dry-run: skipping wait for %s", id)
+		d.SetId(id)
+		return d.Set("json", "")
+	}
+
+	output, err := waitForObjects(resourceArg, selector, namespace, condition,
+		timeout, kubectlCLIConfig)
+	if err != nil {
+		return err
+	}
+	objectsJSON, err := waitResultJSON(output, name != "")
+	if err != nil {
+		return err
+	}
+
+	d.SetId(id)
+	return d.Set("json", objectsJSON)
+}
+
+// Runs `kubectl wait` until the condition is met, waiting for the objects to
+// exist first
+func waitForObjects(resourceArg, selector, namespace, condition string,
+	timeout time.Duration, kubectlCLIConfig *KubectlConfig) (string, error) {
+
+	commandFactory := &CLICommandFactory{KubectlConfig: kubectlCLIConfig}
+	deadline := time.Now().Add(timeout)
+	for {
+		remaining := deadline.Sub(time.Now())
+		// kubectl only accepts whole seconds
+		remaining -= remaining % time.Second
+		if remaining < time.Second {
+			remaining = time.Second
+		}
+
+		stdout := &bytes.Buffer{}
+		waitCommand := commandFactory.CreateWaitCommand(resourceArg, selector,
+			namespace, condition, remaining, stdout)
+		err := waitCommand.RunCommand()
+		if err == nil {
+			return stdout.String(), nil
+		}
+		if condition == "delete" || !isWaitNotFoundError(err) {
+			return "", err
+		}
+		if time.Now().Add(waitPollInterval).After(deadline) {
+			return "", fmt.Errorf("timeout while waiting for %s to exist: %s",
+				resourceArg, err)
+		}
+		log.Printf("[DEBUG] waiting for %s to exist", resourceArg)
+		time.Sleep(waitPollInterval)
+	}
+}
+
+func isWaitNotFoundError(err error) bool {
+	for _, notFound := range waitNotFoundErrors {
+		if strings.Contains(err.Error(), notFound) {
+			return true
+		}
+	}
+	return false
+}
+
+// Encodes the objects printed out by `kubectl wait`, one after the other, as
+// the object waited for by name, or as the list of the objects matching the
+// selector
+func waitResultJSON(output string, byName bool) (string, error) {
+	items := make([]interface{}, 0)
+	decoder := json.NewDecoder(strings.NewReader(output))
+	for {
+		var item interface{}
+		if err := decoder.Decode(&item); err == io.EOF {
+			break
+		} else if err != nil {
+			return "", fmt.Errorf("decoding response: %v", err)
+		}
+		items = append(items, item)
+	}
+
+	if len(items) == 0 {
+		return "", nil
+	}
+	var result interface{} = map[string]interface{}{
+		"apiVersion": "v1", "kind": "List", "items": items}
+	if byName && len(items) == 1 {
+		result = items[0]
+	}
+	objectsJSON, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(objectsJSON), nil
+}
@@ -0,0 +1,293 @@
+package kubectl
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/terraform/helper/schema"
+)
+
+const tracingServiceName = "terraform-provider-kubectl"
+
+// Tracer exports the spans of the provider operations as OTLP/JSON, either
+// to an OTLP/HTTP collector endpoint (e.g. http://localhost:4318/v1/traces)
+// or appended as JSON lines to a file.
+type Tracer struct {
+	Endpoint string
+	File     string
+	Headers  map[string]string
+	Redactor *Redactor
+
+	mu sync.Mutex
+}
+
+// Span is an operation of the provider. The spans of a trace are exported
+// together when its root span ends.
+type Span struct {
+	tracer   *Tracer
+	root     *Span
+	traceID  string
+	spanID   string
+	parentID string
+	name     string
+	start    time.Time
+	end      time.Time
+	attrs    map[string]string
+	err      error
+
+	// finished spans of the trace, only kept by the root span
+	mu    sync.Mutex
+	spans []*Span
+}
+
+func randomID(size int) string {
+	id := make([]byte, size)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id)
+}
+
+// StartSpan starts the root span of a new trace
+func (t *Tracer) StartSpan(name string) *Span {
+	if t == nil {
+		return nil
+	}
+	span := &Span{tracer: t, traceID: randomID(16), spanID: randomID(8),
+		name: name, start: time.Now(), attrs: make(map[string]string)}
+	span.root = span
+	return span
+}
+
+// Child starts a span nested in this one
+func (s *Span) Child(name string) *Span {
+	if s == nil {
+		return nil
+	}
+	return &Span{tracer: s.tracer, root: s.root, traceID: s.traceID,
+		spanID: randomID(8), parentID: s.spanID, name: name,
+		start: time.Now(), attrs: make(map[string]string)}
+}
+
+// SetAttribute records a string attribute on the span
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.attrs[key] = value
+}
+
+// End ends the span, recording the error of the operation if any. Ending
+// the root span exports the whole trace.
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+	s.err = err
+
+	s.root.mu.Lock()
+	s.root.spans = append(s.root.spans, s)
+	spans := s.root.spans
+	s.root.mu.Unlock()
+
+	if s == s.root {
+		s.tracer.export(spans)
+	}
+}
+
+type otlpAttribute struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+func newOTLPAttribute(key, value string) otlpAttribute {
+	attribute := otlpAttribute{Key: key}
+	attribute.Value.StringValue = value
+	return attribute
+}
+
+// Builds the OTLP/JSON export request of the spans
+func (t *Tracer) exportRequest(spans []*Span) map[string]interface{} {
+	otlpSpans := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		otlp := otlpSpan{
+			TraceID:      span.traceID,
+			SpanID:       span.spanID,
+			ParentSpanID: span.parentID,
+			Name:         span.name,
+			// internal span
+			Kind: 1,
+			StartTimeUnixNano: strconv.FormatInt(
+				span.start.UnixNano(), 10),
+			EndTimeUnixNano: strconv.FormatInt(span.end.UnixNano(), 10),
+			Status:          otlpStatus{Code: 1},
+		}
+		for key, value := range span.attrs {
+			otlp.Attributes = append(otlp.Attributes,
+				newOTLPAttribute(key, t.Redactor.Redact(value)))
+		}
+		if span.err != nil {
+			otlp.Status = otlpStatus{Code: 2,
+				Message: t.Redactor.Redact(span.err.Error())}
+		}
+		otlpSpans = append(otlpSpans, otlp)
+	}
+
+	return map[string]interface{}{
+		"resourceSpans": []interface{}{map[string]interface{}{
+			"resource": map[string]interface{}{
+				"attributes": []otlpAttribute{newOTLPAttribute(
+					"service.name", tracingServiceName)},
+			},
+			"scopeSpans": []interface{}{map[string]interface{}{
+				"scope": map[string]interface{}{"name": tracingServiceName},
+				"spans": otlpSpans,
+			}},
+		}},
+	}
+}
+
+// Exports the spans of a trace. Export failures are logged, tracing never
+// failing the provider operations.
+func (t *Tracer) export(spans []*Span) {
+	body, err := json.Marshal(t.exportRequest(spans))
+	if err != nil {
+		log.Printf("[WARN] could not encode trace: %s", err)
+		return
+	}
+
+	if t.File != "" {
+		if err := t.exportToFile(body); err != nil {
+			log.Printf("[WARN] could not write trace to %s: %s", t.File, err)
+		}
+	}
+	if t.Endpoint != "" {
+		if err := t.exportToEndpoint(body); err != nil {
+			log.Printf("[WARN] could not export trace to %s: %s",
+				t.Endpoint, err)
+		}
+	}
+}
+
+func (t *Tracer) exportToFile(body []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	file, err := os.OpenFile(t.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY,
+		0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(body, '\n'))
+	return err
+}
+
+func (t *Tracer) exportToEndpoint(body []byte) error {
+	request, err := http.NewRequest("POST", t.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for key, value := range t.Headers {
+		request.Header.Set(key, value)
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %s", response.Status)
+	}
+	return nil
+}
+
+// Builds the tracer from the tracing block of the provider, or returns nil
+// when tracing is not configured
+func expandTracer(blocks []interface{}, redactor *Redactor) *Tracer {
+	if len(blocks) == 0 || blocks[0] == nil {
+		return nil
+	}
+	block := blocks[0].(map[string]interface{})
+	return &Tracer{
+		Endpoint: block["endpoint"].(string),
+		File:     block["file"].(string),
+		Headers: expandStringMap(
+			block["headers"].(map[string]interface{})),
+		Redactor: redactor,
+	}
+}
+
+// Traces the operations of a resource or data source, the span of the
+// operation being handed to the kubectl commands through the config
+func traceResource(name string, r *schema.Resource) *schema.Resource {
+	traced := func(operation string, d *schema.ResourceData, m interface{},
+		f func(*schema.ResourceData, interface{}) error) error {
+
+		config, ok := m.(*Config)
+		if !ok || config.Tracer == nil {
+			return f(d, m)
+		}
+		span := config.Tracer.StartSpan(name + "." + operation)
+		span.SetAttribute("terraform.id", d.Id())
+		spanConfig := *config
+		spanConfig.span = span
+		err := f(d, &spanConfig)
+		span.End(err)
+		return err
+	}
+
+	if create := r.Create; create != nil {
+		r.Create = func(d *schema.ResourceData, m interface{}) error {
+			return traced("create", d, m, create)
+		}
+	}
+	if read := r.Read; read != nil {
+		r.Read = func(d *schema.ResourceData, m interface{}) error {
+			return traced("read", d, m, read)
+		}
+	}
+	if update := r.Update; update != nil {
+		r.Update = func(d *schema.ResourceData, m interface{}) error {
+			return traced("update", d, m, update)
+		}
+	}
+	if del := r.Delete; del != nil {
+		r.Delete = func(d *schema.ResourceData, m interface{}) error {
+			return traced("delete", d, m, del)
+		}
+	}
+	return r
+}
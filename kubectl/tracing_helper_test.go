@@ -0,0 +1,94 @@
+package kubectl_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/Typeform/terraform-provider-kubectl/kubectl"
+)
+
+type exportedSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Name         string `json:"name"`
+	Status       struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+}
+
+type exportRequest struct {
+	ResourceSpans []struct {
+		ScopeSpans []struct {
+			Spans []exportedSpan `json:"spans"`
+		} `json:"scopeSpans"`
+	} `json:"resourceSpans"`
+}
+
+func exportedSpans(body []byte) []exportedSpan {
+	var request exportRequest
+	Expect(json.Unmarshal(body, &request)).To(Succeed())
+	Expect(request.ResourceSpans).To(HaveLen(1))
+	Expect(request.ResourceSpans[0].ScopeSpans).To(HaveLen(1))
+	return request.ResourceSpans[0].ScopeSpans[0].Spans
+}
+
+var _ = Describe("TracingHelper", func() {
+
+	It("Should export the spans of a trace to the collector", func() {
+		received := make(chan []byte, 1)
+		collector := httptest.NewServer(http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				Expect(r.URL.Path).To(Equal("/v1/traces"))
+				Expect(r.Header.Get("Authorization")).To(Equal("Bearer abc"))
+				body, _ := ioutil.ReadAll(r.Body)
+				received <- body
+			}))
+		defer collector.Close()
+
+		tracer := &Tracer{Endpoint: collector.URL + "/v1/traces",
+			Headers: map[string]string{"Authorization": "Bearer abc"}}
+		root := tracer.StartSpan("kubectl_manifest.create")
+		child := root.Child("apply Deployment/nginx")
+		child.End(errors.New("apply failed"))
+		root.End(nil)
+
+		spans := exportedSpans(<-received)
+		Expect(spans).To(HaveLen(2))
+		Expect(spans[0].Name).To(Equal("apply Deployment/nginx"))
+		Expect(spans[0].ParentSpanID).To(Equal(spans[1].SpanID))
+		Expect(spans[0].TraceID).To(Equal(spans[1].TraceID))
+		Expect(spans[0].Status.Code).To(Equal(2))
+		Expect(spans[0].Status.Message).To(Equal("apply failed"))
+		Expect(spans[1].ParentSpanID).To(Equal(""))
+	})
+
+	It("Should trace commands and append traces to a file", func() {
+		file, err := ioutil.TempFile("", "traces_")
+		Expect(err).To(BeNil())
+		file.Close()
+		defer os.Remove(file.Name())
+
+		tracer := &Tracer{File: file.Name()}
+		root := tracer.StartSpan("kubectl_manifest.read")
+		command := NewCLICommand("true")
+		command.Span = root
+		Expect(command.RunCommand()).To(Succeed())
+		root.End(nil)
+
+		content, err := ioutil.ReadFile(file.Name())
+		Expect(err).To(BeNil())
+		spans := exportedSpans(content)
+		Expect(spans).To(HaveLen(2))
+		Expect(spans[0].Name).To(Equal("true"))
+	})
+})